@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go-bechmark/pkg/bench"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// runSummary is the result of a run written by -resultJson
+type runSummary struct {
+	// Label is the -label of the run
+	Label              string   `json:"label,omitempty"`
+	Host               runHost  `json:"host"`
+	Type               string   `json:"type"`
+	Clients            []string `json:"clients"`
+	ThreadsCount       int      `json:"threadsCount"`
+	SecondsCount       int      `json:"secondsCount"`
+	LineProtocolsCount int      `json:"lineProtocolsCount"`
+	PointsPerCall      int      `json:"pointsPerCall"`
+	// Throughput is the rate of written points per second
+	Throughput  float64          `json:"throughput"`
+	Expected    int              `json:"expected"`
+	Counted     bool             `json:"counted"`
+	Total       int              `json:"total"`
+	Rate        float64          `json:"rate"`
+	Percentiles []jsonPercentile `json:"percentiles"`
+	// FailedBatches is set only for writers that report outcomes of their write requests
+	FailedBatches *int64 `json:"failedBatches,omitempty"`
+}
+
+// runHost describes the machine of the run
+type runHost struct {
+	Hostname  string `json:"hostname"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+	CPUs      int    `json:"cpus"`
+	GoVersion string `json:"goVersion"`
+}
+
+func newRunHost() runHost {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return runHost{
+		Hostname:  hostname,
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		CPUs:      runtime.NumCPU(),
+		GoVersion: runtime.Version(),
+	}
+}
+
+type jsonPercentile struct {
+	Percentile float64 `json:"percentile"`
+	LatencyNs  int64   `json:"latencyNs"`
+}
+
+func newRunSummary(label string, writerType string, threadsCount int, secondsCount int, lineProtocolsCount int, pointsPerCall int, result bench.Result) runSummary {
+	summary := runSummary{
+		Label:              label,
+		Host:               newRunHost(),
+		Type:               writerType,
+		Clients:            clientVersions(),
+		ThreadsCount:       threadsCount,
+		SecondsCount:       secondsCount,
+		LineProtocolsCount: lineProtocolsCount,
+		PointsPerCall:      pointsPerCall,
+		Throughput:         bench.ResultThroughput(result, pointsPerCall),
+		Expected:           result.Expected,
+		Counted:            result.Counted,
+		Total:              result.Total,
+		Rate:               result.Rate,
+	}
+	for _, percentile := range result.Percentiles {
+		summary.Percentiles = append(summary.Percentiles, jsonPercentile{percentile.Percentile, int64(percentile.Latency)})
+	}
+	if result.Stats != nil {
+		failed := result.Stats.FailedBatches
+		summary.FailedBatches = &failed
+	}
+	return summary
+}
+
+// writeRunSummary writes the summary as JSON into the file at path
+func writeRunSummary(path string, summary runSummary) error {
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// printRunSummary prints the summary as JSON into output
+func printRunSummary(output io.Writer, summary runSummary) error {
+	encoder := json.NewEncoder(output)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(summary)
+}
+
+// binaryFlags are the flags of the coordinating process that are not passed to the compared binaries
+var binaryFlags = map[string]bool{"binaryA": true, "binaryB": true, "resultJson": true, "measurementName": true}
+
+// binaryArgs returns the flags set on the command line except binaryFlags, repeatable headers are passed one by one
+func binaryArgs(headers headerFlags) []string {
+	var args []string
+	flag.Visit(func(f *flag.Flag) {
+		switch {
+		case binaryFlags[f.Name]:
+		case f.Name == "header":
+			for _, header := range headers {
+				args = append(args, "-header="+header)
+			}
+		default:
+			args = append(args, "-"+f.Name+"="+f.Value.String())
+		}
+	})
+	return args
+}
+
+// runBinaries runs the binaries one after another with identical args, each into its own measurement
+// with the suffix of its label, and reads their results
+func runBinaries(labels []string, binaries []string, args []string, measurementName string) ([]runSummary, error) {
+	summaries := make([]runSummary, len(binaries))
+	for i, binary := range binaries {
+		file, err := ioutil.TempFile("", "benchmark-result-*.json")
+		if err != nil {
+			return nil, err
+		}
+		file.Close()
+		defer os.Remove(file.Name())
+		fmt.Printf("Running %s: %s\n", labels[i], binary)
+		command := exec.Command(binary, append(append([]string(nil), args...),
+			"-measurementName="+measurementName+"_"+labels[i], "-resultJson="+file.Name())...)
+		command.Stdout = os.Stdout
+		command.Stderr = os.Stderr
+		if err := command.Run(); err != nil {
+			return nil, fmt.Errorf("%s failed: %v", binary, err)
+		}
+		data, err := ioutil.ReadFile(file.Name())
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &summaries[i]); err != nil {
+			return nil, fmt.Errorf("result of %s: %v", binary, err)
+		}
+		fmt.Println()
+	}
+	return summaries, nil
+}
+
+// printBinaries prints the results of the compared binaries and the delta of B against A
+func printBinaries(labels []string, binaries []string, summaries []runSummary) {
+	fmt.Println("Comparison of binaries:")
+	for i, summary := range summaries {
+		fmt.Printf("-> %s: %s (%s)\n", labels[i], binaries[i], strings.Join(summary.Clients, ", "))
+		fmt.Printf("   rate [points/sec]: %.1f", summary.Throughput)
+		if summary.Counted {
+			fmt.Printf(", total: %v (%.1f%%)", summary.Total, summary.Rate)
+		}
+		if summary.FailedBatches != nil {
+			fmt.Printf(", failed batches: %v", *summary.FailedBatches)
+		}
+		fmt.Println()
+	}
+	a, b := summaries[0], summaries[1]
+	if a.Throughput > 0 {
+		fmt.Printf("-> throughput delta:  %+.1f%%\n", (b.Throughput/a.Throughput-1)*100)
+	}
+	for i := 0; i < len(a.Percentiles) && i < len(b.Percentiles); i++ {
+		latencyA, latencyB := time.Duration(a.Percentiles[i].LatencyNs), time.Duration(b.Percentiles[i].LatencyNs)
+		fmt.Printf("-> %-18s A %v, B %v, delta %v\n", fmt.Sprintf("p%v latency:", a.Percentiles[i].Percentile), latencyA, latencyB, latencyB-latencyA)
+	}
+}
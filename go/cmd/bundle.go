@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// redacted replaces secrets in the files of the bundle
+const redacted = "REDACTED"
+
+// secretFlags are the flags whose values are redacted in the bundle
+var secretFlags = map[string]bool{"token": true, "rotateTokens": true}
+
+// secretName matches the names of environment variables and headers whose values are redacted in the bundle
+var secretName = regexp.MustCompile(`(?i)token|password|passwd|secret|key|auth|credential|cookie`)
+
+// writeBundle writes the files reproducing the run into dir: command.sh with the command line, config.json
+// with the effective values of all flags, environment.txt with the environment, result.json with the summary
+// and latencies.txt with the latencies unless they are nil
+func writeBundle(dir string, summary runSummary, latencies [][]time.Duration) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "command.sh"), []byte(bundleCommand(os.Args)), 0755); err != nil {
+		return err
+	}
+	config := map[string]interface{}{"version": version, "commit": commit}
+	flags := map[string]string{}
+	flag.VisitAll(func(f *flag.Flag) {
+		flags[f.Name] = redactFlag(f.Name, f.Value.String())
+	})
+	config["flags"] = flags
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "config.json"), append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "environment.txt"), []byte(bundleEnvironment(os.Environ())), 0644); err != nil {
+		return err
+	}
+	if err := writeRunSummary(filepath.Join(dir, "result.json"), summary); err != nil {
+		return err
+	}
+	if latencies != nil {
+		return dumpLatencies(filepath.Join(dir, "latencies.txt"), latencies)
+	}
+	return nil
+}
+
+// redactFlag returns the value of the flag with secrets redacted, the values of -header are redacted
+// for secret header names
+func redactFlag(name string, value string) string {
+	switch {
+	case value == "":
+		return value
+	case secretFlags[name]:
+		return redacted
+	case name == "header":
+		headers := strings.Split(value, ", ")
+		for i, header := range headers {
+			parts := strings.SplitN(header, ":", 2)
+			if len(parts) == 2 && secretName.MatchString(parts[0]) {
+				headers[i] = parts[0] + ": " + redacted
+			}
+		}
+		return strings.Join(headers, ", ")
+	}
+	return value
+}
+
+// bundleCommand returns the shell script running args with secret flags redacted
+func bundleCommand(args []string) string {
+	quoted := make([]string, len(args))
+	for i := 0; i < len(args); i++ {
+		quoted[i] = shellQuote(args[i])
+		if i == 0 || !strings.HasPrefix(args[i], "-") {
+			continue
+		}
+		name := strings.TrimLeft(args[i], "-")
+		if parts := strings.SplitN(name, "=", 2); len(parts) == 2 {
+			quoted[i] = shellQuote(args[i][:len(args[i])-len(parts[1])] + redactFlag(parts[0], parts[1]))
+		} else if i+1 < len(args) && flag.Lookup(name) != nil && !isBoolFlag(name) {
+			i++
+			quoted[i] = shellQuote(redactFlag(name, args[i]))
+		}
+	}
+	return "#!/bin/sh\n" + strings.Join(quoted, " ") + "\n"
+}
+
+// isBoolFlag tells if the flag takes no value like -skipCount
+func isBoolFlag(name string) bool {
+	value, ok := flag.Lookup(name).Value.(interface{ IsBoolFlag() bool })
+	return ok && value.IsBoolFlag()
+}
+
+// shellQuote quotes the argument for sh unless it is safe
+func shellQuote(arg string) string {
+	if arg != "" && strings.Trim(arg, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_=./:,@+%") == "" {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+// bundleEnvironment returns the sorted environment with values of secret variables redacted
+func bundleEnvironment(environment []string) string {
+	lines := make([]string, 0, len(environment)+1)
+	for _, variable := range environment {
+		if parts := strings.SplitN(variable, "=", 2); len(parts) == 2 && secretName.MatchString(parts[0]) {
+			variable = parts[0] + "=" + redacted
+		}
+		lines = append(lines, variable)
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n") + "\n"
+}
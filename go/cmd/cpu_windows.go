@@ -0,0 +1,25 @@
+//go:build windows
+// +build windows
+
+package main
+
+import (
+	"syscall"
+	"time"
+)
+
+// processCPUTime returns user and system CPU time consumed by the process
+func processCPUTime() (time.Duration, bool) {
+	handle, err := syscall.GetCurrentProcess()
+	if err != nil {
+		return 0, false
+	}
+	var creation, exit, kernel, user syscall.Filetime
+	if err := syscall.GetProcessTimes(handle, &creation, &exit, &kernel, &user); err != nil {
+		return 0, false
+	}
+	// Filetime counts 100-nanosecond intervals
+	kernelTicks := int64(kernel.HighDateTime)<<32 | int64(kernel.LowDateTime)
+	userTicks := int64(user.HighDateTime)<<32 | int64(user.LowDateTime)
+	return time.Duration((kernelTicks + userTicks) * 100), true
+}
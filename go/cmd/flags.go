@@ -0,0 +1,502 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go-bechmark/pkg/bench"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// headerFlags collects repeatable -header Key:Value flags
+type headerFlags []string
+
+func (h *headerFlags) String() string {
+	return strings.Join(*h, ", ")
+}
+
+func (h *headerFlags) Set(value string) error {
+	if !strings.Contains(value, ":") {
+		return fmt.Errorf("header %q is not in the Key:Value format", value)
+	}
+	*h = append(*h, value)
+	return nil
+}
+
+// apply sets collected headers into header
+func (h headerFlags) apply(header http.Header) {
+	for _, value := range h {
+		parts := strings.SplitN(value, ":", 2)
+		header.Set(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]))
+	}
+}
+
+// flags are the command line flags of the benchmark
+type flags struct {
+	writerType                   string
+	sinkUrl                      string
+	threadsCount                 int
+	secondsCount                 int
+	batchSize                    uint
+	authToken                    string
+	org                          string
+	bucket                       string
+	cloud                        bool
+	lineProtocolsCount           int
+	skipCount                    bool
+	countDelaySeconds            int
+	countStabilize               bool
+	countTimeShards              int
+	countStabilizeTimeoutSeconds int
+	measurementName              string
+	paddingBytes                 int
+	serializeVersion             string
+	precision                    string
+	timestampScale               int64
+	percentilesList              string
+	extraHeaders                 headerFlags
+	printVersion                 bool
+	urls                         string
+	requireEmpty                 bool
+	appendMode                   bool
+	concurrentReads              int
+	checkpointCountInterval      int
+	measurementSwitchEvery       int
+	model                        string
+	clientDelayMicros            int
+	arrival                      string
+	rampDownSeconds              int
+	pointsPerCall                int
+	ignoreCloseError             bool
+	tuneType                     string
+	replayType                   string
+	replayCsv                    string
+	replayMapping                string
+	autotuneStep                 int
+	targetP99Millis              float64
+	targetErrorRate              float64
+	batchSizeMin                 uint
+	batchSizeMax                 uint
+	sweepMinBatchSize            uint
+	sweepMaxBatchSize            uint
+	sweepFactor                  uint
+	sweepPlateau                 float64
+	debugSampleRate              float64
+	onError                      string
+	maxPendingPoints             int
+	pendingPolicy                string
+	v2WriteMode                  string
+	contentType                  string
+	writeConsistency             string
+	countLang                    string
+	countRange                   string
+	lightweightCount             bool
+	countField                   string
+	tokenRotateSeconds           int
+	rotateTokens                 string
+	runTag                       bool
+	defaultTags                  string
+	fieldKeyChurn                int
+	intFields                    bool
+	uintFields                   bool
+	boolFields                   bool
+	valueExpr                    string
+	stringFieldValues            string
+	fieldSpec                    string
+	floatFormat                  string
+	duplicateRate                float64
+	orderedTimestamps            bool
+	verifyOrder                  bool
+	verifyOrderSeries            int
+	interleaveSeries             bool
+	selfReport                   bool
+	selfReportMeasurement        string
+	selfReportBucket             string
+	idleConnTimeoutSeconds       int
+	keepAliveSeconds             int
+	printCountQuery              bool
+	checkClockSkew               bool
+	skipHealthCheck              bool
+	checkLeaks                   bool
+	throughputTimeline           int
+	deadLetterFile               string
+	batchTraceOut                string
+	latencyDump                  string
+	reportAllocs                 bool
+	reportSyncOverhead           bool
+	reportGoroutines             bool
+	reportSchedLatency           bool
+	reusePoints                  bool
+	retryBudget                  int
+	clientPerWorker              bool
+	writeTimeout                 time.Duration
+	rawClient                    string
+	lineSeparator                string
+	tcpNoDelay                   bool
+	udpAddr                      string
+	udpPayloadBytes              int
+	hostHeader                   string
+	unixSocket                   string
+	otelEndpoint                 string
+	detectBottleneck             bool
+	output                       string
+	repeat                       int
+	binaryA                      string
+	binaryB                      string
+	label                        string
+	bundleOut                    string
+	bundleLatencies              bool
+	resultJson                   string
+	quiet                        bool
+}
+
+// parseFlags parses the command line flags
+func parseFlags() flags {
+	var f flags
+	flag.StringVar(&f.writerType, "type", "CLIENT_GO_V2", "Type of writer (default 'CLIENT_GO_V2'; CLIENT_GO_V1, CLIENT_GO_V2, HTTP_GO_V1, HTTP_GO_V2, HTTP_SINK, UDP_V1 - line protocol into the UDP listener of InfluxDB 1.x, SERIALIZE, AUTOTUNE, BATCH_SWEEP, COMPARE_V2, ALL, COUNT_CHECK - CLIENT_GO_V1 and CLIENT_GO_V2 write the same load and their counts are compared, REPLAY, SELFTEST - a short run of every InfluxDB writer against an in-process fake InfluxDB checking that all points arrive)")
+	flag.StringVar(&f.sinkUrl, "sinkUrl", "", "URL that the HTTP_SINK type posts batches to, any HTTP server answering 2xx without InfluxDB (default http://localhost:8080)")
+	flag.IntVar(&f.threadsCount, "threadsCount", 2000, "how much Thread use to write into InfluxDB")
+	flag.IntVar(&f.secondsCount, "secondsCount", 30, "how long write into InfluxDB")
+	flag.UintVar(&f.batchSize, "batchSize", 1000, "batch size")
+	flag.StringVar(&f.authToken, "token", "my-token", "InfluxDB 2 authentication token")
+	flag.StringVar(&f.org, "org", "my-org", "organization of V2 writers, its ID with -cloud")
+	flag.StringVar(&f.bucket, "bucket", "my-bucket", "bucket of V2 writers")
+	flag.BoolVar(&f.cloud, "cloud", false, "write into InfluxDB Cloud at -urls, the V2 writers address -org by its ID and HTTP_GO_V2 waits for the Retry-After of rate-limited writes")
+	flag.IntVar(&f.lineProtocolsCount, "lineProtocolsCount", 100, "how much data writes in one batch")
+	flag.BoolVar(&f.skipCount, "skipCount", false, "skip counting count")
+	flag.IntVar(&f.countDelaySeconds, "countDelaySeconds", 0, "wait the given seconds after the writes before counting, so that ingestion and indexing catch up (default 0 - count right away)")
+	flag.BoolVar(&f.countStabilize, "countStabilize", false, "repeat the count every second until it doesn't change, up to -countStabilizeTimeoutSeconds, to tell ingest lag from lost writes")
+	flag.IntVar(&f.countTimeShards, "countTimeShards", 0, "count by the given count of concurrent queries of contiguous windows of the time range of the points and sum them, to verify huge datasets quickly (default 0 - one query)")
+	flag.IntVar(&f.countStabilizeTimeoutSeconds, "countStabilizeTimeoutSeconds", 30, "how long -countStabilize repeats the count")
+	flag.StringVar(&f.measurementName, "measurementName", fmt.Sprintf("sensor_%d", time.Now().UnixNano()), "writer measure destination")
+	flag.IntVar(&f.paddingBytes, "paddingBytes", 0, "size of random string field appended to each point (default 0 - no padding)")
+	flag.StringVar(&f.serializeVersion, "serializeVersion", "CLIENT_GO_V2", "client used to serialize points in the SERIALIZE type (CLIENT_GO_V1, CLIENT_GO_V2)")
+	flag.StringVar(&f.precision, "precision", "ns", "precision of written timestamps (ns, us, ms, s), generated timestamps are truncated to it and the expected size excludes points whose timestamps collapse")
+	flag.Int64Var(&f.timestampScale, "timestampScale", 1, "divisor applied to the generated timestamp before sending, independent of the write precision")
+	flag.StringVar(&f.percentilesList, "percentiles", "50,90,99,99.9", "comma-separated list of reported write latency percentiles")
+	flag.Var(&f.extraHeaders, "header", "extra HTTP header Key:Value added to requests of HTTP_GO_V1 and HTTP_GO_V2 writers (repeatable)")
+	flag.BoolVar(&f.printVersion, "version", false, "print the tool version, git commit and versions of the InfluxDB clients and exit")
+	flag.StringVar(&f.urls, "urls", "", "comma-separated list of InfluxDB URLs, threads are distributed round-robin across them and points are counted on each of them (default 'http://localhost:8086' for V1 and 'http://localhost:9999' for V2 writers)")
+	flag.BoolVar(&f.requireEmpty, "requireEmpty", false, "count the points of the measurement before the run and exit if there are any, so that stale points of a reused measurement don't inflate the rate")
+	flag.BoolVar(&f.appendMode, "appendMode", false, "count the points of the measurement before the run and subtract them from the total, to append to a reused measurement, points overwriting existing points of the same series and timestamp are not counted again")
+	flag.IntVar(&f.concurrentReads, "concurrentReads", 0, "count of goroutines executing the count query of the measurement in a loop during the run, to measure writes mixed with reads and the read throughput and latency (default 0 - no reads)")
+	flag.IntVar(&f.checkpointCountInterval, "checkpointCountInterval", 0, "count the points in InfluxDB every given seconds of the run and print them with the count of points written so far (default 0 - no checkpoints)")
+	flag.IntVar(&f.measurementSwitchEvery, "measurementSwitchEvery", 0, "switch the measurement of the points of a thread every given points between -measurementName and the same name with the _alt suffix, the points of both are counted (default 0 - one measurement)")
+	flag.StringVar(&f.model, "model", "burst", "coordination of the threads (burst - every thread writes the points of every second back to back and sleeps, pipeline - a generator goroutine passes the Write calls to the threads by a channel and they write continuously without pacing)")
+	flag.IntVar(&f.clientDelayMicros, "clientDelayMicros", 0, "sleep the given microseconds in every Write call before handing the points off to the client, to simulate a slow application (default 0 - no delay)")
+	flag.StringVar(&f.arrival, "arrival", "uniform", "pacing of Write calls of a thread (uniform - points of every second back to back followed by a sleep, poisson - the calls of every second spread over it at random times of a Poisson process, bursty but writing the same points per second)")
+	flag.IntVar(&f.rampDownSeconds, "rampDownSeconds", 0, "continue the run for the given seconds with the load decreasing linearly to zero, written into the measurement with the _rampdown suffix and excluded from the results (default 0 - abrupt stop)")
+	flag.IntVar(&f.pointsPerCall, "pointsPerCall", 1, "how much points are passed to one Write call of the writer")
+	flag.BoolVar(&f.ignoreCloseError, "ignoreCloseError", false, "report an error of closing the writer as a warning instead of exiting with non-zero status")
+	flag.StringVar(&f.tuneType, "tuneType", "HTTP_GO_V2", "type of writer tuned by the AUTOTUNE and BATCH_SWEEP types (CLIENT_GO_V1, CLIENT_GO_V2, HTTP_GO_V1, HTTP_GO_V2), AUTOTUNE takes its error rate from the failed requests of HTTP_GO_V1 and HTTP_GO_V2 and rejects the CLIENT_GO writers")
+	flag.StringVar(&f.replayType, "replayType", "CLIENT_GO_V2", "type of writer replaying the CSV file in the REPLAY type (CLIENT_GO_V1, CLIENT_GO_V2, HTTP_GO_V1, HTTP_GO_V2, HTTP_SINK)")
+	flag.StringVar(&f.replayCsv, "replayCsv", "", "CSV file with a header row replayed by the REPLAY type in batches of batchSize rows, the replayed points are counted by -countField that has to be one of the mapped fields")
+	flag.StringVar(&f.replayMapping, "replayMapping", "", "mapping of CSV columns of the REPLAY type like \"measurement=cpu;tags=host,region;fields=usage,idle;time=ts\", time is RFC3339 or nanoseconds, default is the time of reading")
+	flag.IntVar(&f.autotuneStep, "autotuneStep", 10, "how much workers are added by the AUTOTUNE type after a stable second")
+	flag.Float64Var(&f.targetP99Millis, "targetP99Millis", 100, "highest p99 write latency in milliseconds of a stable second in the AUTOTUNE type")
+	flag.Float64Var(&f.targetErrorRate, "targetErrorRate", 0.01, "highest ratio of failed batches of a stable second in the AUTOTUNE type and of a run in the BATCH_SWEEP type")
+	flag.UintVar(&f.batchSizeMin, "batchSizeMin", 0, "lowest size of the batches of HTTP_GO_V1, HTTP_GO_V2, HTTP_SINK and UDP_V1 drawn uniformly up to -batchSizeMax for every batch (default 0 - batchSize)")
+	flag.UintVar(&f.batchSizeMax, "batchSizeMax", 0, "highest size of the batches of HTTP_GO_V1, HTTP_GO_V2, HTTP_SINK and UDP_V1 drawn for every batch, the distribution of the sent sizes is reported (default 0 - every batch has batchSize)")
+	flag.UintVar(&f.sweepMinBatchSize, "sweepMinBatchSize", 100, "first batch size of the BATCH_SWEEP type, each run of -secondsCount seconds multiplies it by -sweepFactor")
+	flag.UintVar(&f.sweepMaxBatchSize, "sweepMaxBatchSize", 100000, "highest batch size of the BATCH_SWEEP type")
+	flag.UintVar(&f.sweepFactor, "sweepFactor", 2, "factor of the batch size between runs of the BATCH_SWEEP type")
+	flag.Float64Var(&f.sweepPlateau, "sweepPlateau", 0.05, "lowest relative throughput gain over the best smaller batch size that continues the BATCH_SWEEP type")
+	flag.Float64Var(&f.debugSampleRate, "debugSampleRate", 0, "fraction (0.0-1.0) of HTTP_GO_V1 and HTTP_GO_V2 write requests logged with their body and response")
+	flag.StringVar(&f.onError, "onError", "drop", "policy of failed writes of HTTP_GO_V1, HTTP_GO_V2, HTTP_SINK and the sync writer of COMPARE_V2 (requeue - put the points back for a later attempt, drop - discard them, abort - stop the run)")
+	flag.IntVar(&f.maxPendingPoints, "maxPendingPoints", 0, "bound points written into the async WriteApi of CLIENT_GO_V2 and not flushed yet, further points wait for a flush or are dropped by -pendingPolicy (default 0 - no bound)")
+	flag.StringVar(&f.pendingPolicy, "pendingPolicy", "block", "what happens to points beyond -maxPendingPoints (block - wait for a flush, drop - discard them)")
+	flag.StringVar(&f.v2WriteMode, "v2WriteMode", "point", "how the CLIENT_GO_V2 writer passes points to the client (point - WritePoint of structs, record - WriteRecord of line protocol strings)")
+	flag.StringVar(&f.contentType, "contentType", "text/plain; charset=utf-8", "Content-Type header of requests of HTTP_GO_V1, HTTP_GO_V2 and HTTP_SINK writers to test servers and gateways checking it, none sends requests without the header")
+	flag.StringVar(&f.writeConsistency, "writeConsistency", "", "write consistency of clustered InfluxDB Enterprise passed by CLIENT_GO_V1 and HTTP_GO_V1 writers (one, quorum, all, any; default '' - the server default)")
+	flag.StringVar(&f.countLang, "countLang", "flux", "language of the V2 count query (flux, influxql - uses the 1.x compatibility endpoint)")
+	flag.StringVar(&f.countRange, "countRange", "epoch", "range of the flux count query of V2 writers (epoch - from the epoch to now(), window - the time window of the written points computed from the generation parameters, or a relative start like -1h)")
+	flag.BoolVar(&f.lightweightCount, "lightweightCount", false, "count the values of -countField by the flux query without pivot, cheaper for a shared server and exact as long as every point has the field (the InfluxQL query is lightweight already)")
+	flag.StringVar(&f.countField, "countField", "temperature", "field whose values are counted to verify the written points")
+	flag.IntVar(&f.tokenRotateSeconds, "tokenRotateSeconds", 0, "rebuild the write API of the CLIENT_GO_V2 writer with the next token every given seconds (default 0 - no rotation)")
+	flag.StringVar(&f.rotateTokens, "rotateTokens", "", "comma-separated list of tokens rotated after the -token by -tokenRotateSeconds")
+	flag.BoolVar(&f.runTag, "runTag", false, "add a \"run\" tag with a unique value of the run to every point and count only points of the run")
+	flag.StringVar(&f.defaultTags, "defaultTags", "", "comma-separated key=value tags added to every point by the default tags of the V2 client of CLIENT_GO_V2 and COMPARE_V2 in the point v2WriteMode, the points having them are counted after the run (default '' - no default tags)")
+	flag.IntVar(&f.fieldKeyChurn, "fieldKeyChurn", 0, "add a field whose key changes every given points of a thread (temperature_0, temperature_1, ...) to grow the field keys over the run (default 0 - no churn)")
+	flag.BoolVar(&f.intFields, "intFields", false, "write the temperature field as an integer (123i in line protocol of the raw writers) instead of a string, the measurement must not contain the string field yet")
+	flag.BoolVar(&f.uintFields, "uintFields", false, "add the unsigned integer field counter to every point (123u in line protocol of the raw writers)")
+	flag.BoolVar(&f.boolFields, "boolFields", false, "add the boolean field active to every point")
+	flag.StringVar(&f.valueExpr, "valueExpr", "", "expression generating the values of the numeric fields of -fieldSpec without own expression (like \"temp:float=sin(t/10)*50\"), with + - * / %, t and counter - the iteration of the point, pi, sin, cos, abs, sqrt, floor, min, max and random(low,high), like \"sin(t/100)*50+random(0,5)\" (default empty - random or unique values)")
+	flag.StringVar(&f.stringFieldValues, "stringFieldValues", "", "comma-separated values cycled by the string fields of -fieldSpec without own values (like \"status:string=ok|warn|error\"), to compare low-cardinality strings with the unique ones (default empty - unique strings)")
+	flag.StringVar(&f.fieldSpec, "fieldSpec", "", "comma-separated fields of every point with their types (float, int, uint, bool, string) replacing the temperature field, like \"temp:float,count:int,total:uint,ok:bool,name:string,status:string=ok|warn|error\" where string fields cycle the values after = and numeric fields are generated by the expression after = (see -valueExpr), points are counted on the first field instead of -countField")
+	flag.StringVar(&f.floatFormat, "floatFormat", "", "fmt verb formatting the values of float fields of -fieldSpec serialized by HTTP_GO_V1, HTTP_GO_V2, HTTP_SINK, UDP_V1 and the record v2WriteMode, like %g, %.2f or %.15g, to measure the effect of the precision on the payload size (default '' - the shortest representation without an exponent)")
+	flag.Float64Var(&f.duplicateRate, "duplicateRate", 0, "fraction (0.0-1.0) of points that reuse the series and timestamp of the previous point of the thread to overwrite it")
+	flag.BoolVar(&f.orderedTimestamps, "orderedTimestamps", false, "give every point the next tick of a clock shared by all threads, timestamps are globally unique and increasing - an append-only workload that accesses the storage differently than the default timestamps repeated by each thread")
+	flag.BoolVar(&f.verifyOrder, "verifyOrder", false, "check after the run of -orderedTimestamps that timestamps of -verifyOrderSeries sampled series strictly increase and that the points of all series fill every tick of the clock without gaps")
+	flag.IntVar(&f.verifyOrderSeries, "verifyOrderSeries", 10, "how many series are sampled by -verifyOrder")
+	flag.BoolVar(&f.interleaveSeries, "interleaveSeries", false, "mix points of many series (ids) into batches instead of writing a contiguous block of one series per thread")
+	flag.BoolVar(&f.selfReport, "selfReport", false, "write the summary of the run as a point into InfluxDB 2")
+	flag.StringVar(&f.selfReportMeasurement, "selfReportMeasurement", "benchmark_results", "measurement of the self report point")
+	flag.StringVar(&f.selfReportBucket, "selfReportBucket", "my-bucket", "bucket of the self report point")
+	flag.IntVar(&f.idleConnTimeoutSeconds, "idleConnTimeoutSeconds", 90, "how long an idle connection of HTTP_GO_V1 and HTTP_GO_V2 writers is kept open, 0 means no limit")
+	flag.IntVar(&f.keepAliveSeconds, "keepAliveSeconds", 30, "period of TCP keep-alive probes of HTTP_GO_V1 and HTTP_GO_V2 connections, 0 disables them")
+	flag.BoolVar(&f.printCountQuery, "printCountQuery", false, "print the Flux or InfluxQL query that counts the written points and exit, -countTimeShards adds a time condition to it")
+	flag.BoolVar(&f.checkClockSkew, "checkClockSkew", false, "compare the local clock with the Date header of the health check and warn about the skew that breaks time ranges of count queries")
+	flag.BoolVar(&f.skipHealthCheck, "skipHealthCheck", false, "skip the check that InfluxDB is up (/health for V2, /ping for V1) before the run")
+	flag.BoolVar(&f.checkLeaks, "checkLeaks", false, "report goroutines left running after the writers are closed with their stacks")
+	flag.IntVar(&f.throughputTimeline, "throughputTimeline", 0, "print the points of Write calls finished within every given seconds of the run as a table with a sparkline to reveal ramp-ups, stalls and degradation (default 0 - no timeline)")
+	flag.StringVar(&f.deadLetterFile, "deadLetterFile", "", "file that receives the points of HTTP_GO_V1, HTTP_GO_V2, HTTP_SINK and the sync writer of COMPARE_V2 that failed after the retries and were dropped or aborted by -onError, or left requeued at the end of the run, as line protocol with a comment line of the error before every failed batch (default '' - the points are only counted)")
+	flag.StringVar(&f.batchTraceOut, "batchTraceOut", "", "file that receives a JSON line with the start offset, duration, size and result of every batch of HTTP_GO_V1, HTTP_GO_V2, HTTP_SINK, CLIENT_GO_V1 and blocking writes, or of every flush of the async CLIENT_GO_V2 whose own batches are not observable (default '' - no trace)")
+	flag.StringVar(&f.latencyDump, "latencyDump", "", "file that receives every recorded write latency in nanoseconds, one per line, for external analysis like HdrHistogram tools (default '' - no dump)")
+	flag.BoolVar(&f.reportAllocs, "reportAllocs", false, "read runtime memory statistics before and after the run and print heap allocations and bytes allocated per Write call of the writer")
+	flag.BoolVar(&f.reportSyncOverhead, "reportSyncOverhead", false, "measure the aggregate time the threads spend in selects of the stop of the run, pacing and waiting for shared locks of the writer, and print it next to the time of Write calls to tell the overhead of the harness from I/O")
+	flag.BoolVar(&f.reportGoroutines, "reportGoroutines", false, "sample the count of goroutines every 100ms during the run and print its peak and average, to compare the goroutines of the writers and their clients")
+	flag.BoolVar(&f.reportSchedLatency, "reportSchedLatency", false, "sample how late goroutines wake up from a timer during the run and print max/avg scheduling delay")
+	flag.BoolVar(&f.reusePoints, "reusePoints", false, "serialize the first point of every series once and write it again with only the timestamps varying, to measure the send ceiling without generating points (HTTP_GO_V1, HTTP_GO_V2, HTTP_SINK and the record v2WriteMode), the written fields are static")
+	flag.IntVar(&f.retryBudget, "retryBudget", 0, "total count of retries of failed write requests of HTTP_GO_V1, HTTP_GO_V2 and HTTP_SINK writers across the run, retries wait by an exponential backoff from 100ms up to 5s or by the Retry-After of rate-limited requests, failures after the budget is used up are not retried (default 0 - no retries)")
+	flag.BoolVar(&f.clientPerWorker, "clientPerWorker", false, "give every thread its own writer with its own client (and WriteApi of CLIENT_GO_V2) instead of sharing one")
+	flag.DurationVar(&f.writeTimeout, "writeTimeout", 0, "timeout of every write request of HTTP_GO_V1, HTTP_GO_V2, HTTP_SINK and the sync writer of COMPARE_V2 including its retries, writes during the run are also cut at its end so that late retries don't delay the results (default 0 - no timeout)")
+	flag.StringVar(&f.rawClient, "rawClient", "nethttp", "HTTP client of HTTP_GO_V1, HTTP_GO_V2 and HTTP_SINK writers (nethttp - net/http of the standard library, fasthttp - valyala/fasthttp without connection setup, -debugSampleRate and -otelEndpoint propagation)")
+	flag.StringVar(&f.lineSeparator, "lineSeparator", `\n`, "separator of lines in batches of HTTP_GO_V1, HTTP_GO_V2 and HTTP_SINK writers with Go escapes like \\r\\n, to test whether servers and proxies rewriting line endings reject batches")
+	flag.BoolVar(&f.tcpNoDelay, "tcpNoDelay", true, "set TCP_NODELAY on connections of HTTP_GO_V1, HTTP_GO_V2 and HTTP_SINK writers like Go does by default, false enables Nagle's algorithm to measure its effect on small batches")
+	flag.StringVar(&f.udpAddr, "udpAddr", "", "host:port of the UDP listener of InfluxDB 1.x written by UDP_V1, it must write into the iot_writes database, -countDelaySeconds lets it flush its batches before the count (default '' - the host of the V1 URL and port 8089)")
+	flag.IntVar(&f.udpPayloadBytes, "udpPayloadBytes", bench.DefaultUDPPayloadBytes, "maximum size of datagrams of UDP_V1, batches are split into datagrams of whole lines and longer lines are dropped, keep it below the read buffer of the listener")
+	flag.StringVar(&f.hostHeader, "hostHeader", "", "Host header of write requests of HTTP_GO_V1, HTTP_GO_V2 and HTTP_SINK writers, independent of the dialed URL, to direct them through a load balancer to one node of a cluster, the points are counted through the URL (default '' - the host of the URL)")
+	flag.StringVar(&f.unixSocket, "unixSocket", "", "path of the Unix domain socket of a local InfluxDB dialed by HTTP_GO_V1, HTTP_GO_V2 and HTTP_SINK writers instead of TCP, points are still counted over TCP (default '' - TCP)")
+	flag.StringVar(&f.otelEndpoint, "otelEndpoint", "", "OTLP/HTTP traces endpoint of an OpenTelemetry collector, like http://localhost:4318/v1/traces, receiving a span of every batch of HTTP_GO_V1, HTTP_GO_V2 and HTTP_SINK writers")
+	flag.BoolVar(&f.detectBottleneck, "detectBottleneck", false, "compare CPU utilization of the process and time blocked in Write calls with the throughput and print whether the run is client-bound or server/network-bound")
+	flag.StringVar(&f.output, "output", "text", "format of the final results (text, markdown - adds a GitHub-flavored Markdown table of the results, json - prints the result of a single run as JSON into stdout and all other output into stderr)")
+	flag.IntVar(&f.repeat, "repeat", 1, "run the benchmark the given times, each repetition by a new writer into the measurement with the _1, _2, ... suffix, and print mean and standard deviation of throughput, latency percentiles and error rates")
+	flag.StringVar(&f.binaryA, "binaryA", "", "path of a separately built binary of this benchmark, like one built with another version of a client library, run by the given flags and compared with -binaryB")
+	flag.StringVar(&f.binaryB, "binaryB", "", "path of the second binary compared with -binaryA, both run one after another with the same flags, each into the measurement with the _A or _B suffix")
+	flag.StringVar(&f.label, "label", "", "label of the run added to the JSON result and the self report to tell apart results collected across machines")
+	flag.StringVar(&f.bundleOut, "bundleOut", "", "directory that receives command.sh, config.json with the values of all flags, environment.txt and result.json of the run of a single writer type with secrets redacted, to reproduce the run or attach it to a bug report (default '' - no bundle)")
+	flag.BoolVar(&f.bundleLatencies, "bundleLatencies", false, "add latencies.txt with every recorded write latency in nanoseconds to the -bundleOut directory")
+	flag.StringVar(&f.resultJson, "resultJson", "", "file that receives the results of the run of a single writer type as JSON, used by -binaryA and -binaryB to collect the results")
+	flag.BoolVar(&f.quiet, "quiet", false, "suppress progress and non-essential output, print only the final results")
+	flag.Parse()
+	return f
+}
+
+// clientType returns the type of the writers, AUTOTUNE and BATCH_SWEEP tune the writers of tuneType
+// and REPLAY replays by the writers of replayType
+func (f flags) clientType() string {
+	switch f.writerType {
+	case "AUTOTUNE", "BATCH_SWEEP":
+		return f.tuneType
+	case "REPLAY":
+		return f.replayType
+	}
+	return f.writerType
+}
+
+// endpoints returns the trimmed urls, it is nil without urls
+func (f flags) endpoints() []string {
+	if f.urls == "" {
+		return nil
+	}
+	endpoints := strings.Split(f.urls, ",")
+	for i := range endpoints {
+		endpoints[i] = strings.TrimSpace(endpoints[i])
+	}
+	return endpoints
+}
+
+// firstUrl returns the first of the urls, it is empty without urls
+func (f flags) firstUrl() string {
+	if endpoints := f.endpoints(); len(endpoints) > 0 {
+		return endpoints[0]
+	}
+	return ""
+}
+
+// pointOptions returns the validated options of the generated points
+func (f flags) pointOptions() (bench.PointOptions, error) {
+	fields, err := bench.ParseFields(f.fieldSpec, f.stringFieldValues, f.valueExpr)
+	if err != nil {
+		return bench.PointOptions{}, err
+	}
+	floats, err := bench.ParseFloatFormat(f.floatFormat)
+	if err != nil {
+		return bench.PointOptions{}, err
+	}
+	precision, err := bench.ParsePrecision(f.precision)
+	if err != nil {
+		return bench.PointOptions{}, err
+	}
+	points := bench.PointOptions{
+		PaddingBytes:     f.paddingBytes,
+		TimestampScale:   f.timestampScale,
+		InterleaveSeries: f.interleaveSeries,
+		SeriesCount:      f.threadsCount,
+		FieldKeyChurn:    f.fieldKeyChurn,
+		DuplicateRate:    f.duplicateRate,
+		IntFields:        f.intFields,
+		UintFields:       f.uintFields,
+		BoolFields:       f.boolFields,
+		Fields:           fields,
+		Precision:        precision,
+		FloatFormat:      floats,
+	}
+	if f.orderedTimestamps {
+		clock := time.Now().UnixNano()
+		points.OrderedClock = &clock
+	}
+	if f.runTag {
+		points.RunTag = strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return points, points.Validate()
+}
+
+// writerConfig returns the settings of the writers of the points, the files of the batch trace
+// and the dead letters are opened by the run
+func (f flags) writerConfig(points bench.PointOptions) (bench.WriterConfig, error) {
+	separator, err := strconv.Unquote(`"` + f.lineSeparator + `"`)
+	if err != nil || separator == "" {
+		return bench.WriterConfig{}, fmt.Errorf("unsupported lineSeparator: %v", f.lineSeparator)
+	}
+	tags, err := bench.ParseTags(f.defaultTags)
+	if err != nil {
+		return bench.WriterConfig{}, err
+	}
+	var start, stop int64
+	if f.countRange == "window" {
+		start, stop = points.TimeWindow(f.threadsCount, f.lineProtocolsCount, (f.secondsCount+f.rampDownSeconds+1)*f.lineProtocolsCount-1)
+	}
+	countStart, countStop, err := bench.ParseCountRange(f.countRange, start, stop)
+	if err != nil {
+		return bench.WriterConfig{}, err
+	}
+	headers := http.Header{}
+	f.extraHeaders.apply(headers)
+	config := bench.WriterConfig{
+		AuthToken:           f.authToken,
+		Org:                 f.org,
+		Bucket:              f.bucket,
+		Cloud:               f.cloud,
+		BatchSize:           f.batchSize,
+		BatchSizeMin:        f.batchSizeMin,
+		BatchSizeMax:        f.batchSizeMax,
+		ThreadsCount:        f.threadsCount,
+		Points:              points,
+		ExtraHeaders:        headers,
+		DefaultTags:         tags,
+		DebugSampleRate:     f.debugSampleRate,
+		CountLang:           f.countLang,
+		LightweightCount:    f.lightweightCount,
+		CountStart:          countStart,
+		CountStop:           countStop,
+		CountField:          f.countField,
+		KeepAlive:           time.Duration(f.keepAliveSeconds) * time.Second,
+		IdleConnTimeout:     time.Duration(f.idleConnTimeoutSeconds) * time.Second,
+		TokenRotateInterval: time.Duration(f.tokenRotateSeconds) * time.Second,
+		RotateTokens:        bench.ParseStringValues(f.rotateTokens, ","),
+		UnixSocket:          f.unixSocket,
+		HostHeader:          f.hostHeader,
+		UDPAddr:             f.udpAddr,
+		UDPPayloadBytes:     f.udpPayloadBytes,
+		Nagle:               !f.tcpNoDelay,
+		WriteConsistency:    f.writeConsistency,
+		ContentType:         f.contentType,
+		LineSeparator:       separator,
+		RawClient:           f.rawClient,
+		WriteTimeout:        f.writeTimeout,
+		V2WriteMode:         f.v2WriteMode,
+		OnError:             f.onError,
+		MaxPendingPoints:    f.maxPendingPoints,
+		PendingPolicy:       f.pendingPolicy,
+		ReportLockWait:      f.reportSyncOverhead,
+		ReusePoints:         f.reusePoints,
+		Tracing:             f.otelEndpoint != "",
+	}
+	if len(points.Fields) > 0 {
+		// the points are counted on the first field of fieldSpec
+		config.CountField = points.Fields[0].Key
+	}
+	if f.keepAliveSeconds == 0 {
+		config.KeepAlive = -1
+	}
+	if f.retryBudget != 0 {
+		config.Retries = bench.NewRetryBudget(int64(f.retryBudget))
+	}
+	return config, nil
+}
+
+// loadConfig returns the load of the types running their own writers
+func (f flags) loadConfig(percentiles []float64) bench.Config {
+	return bench.Config{
+		ThreadsCount:       f.threadsCount,
+		SecondsCount:       f.secondsCount,
+		MeasurementName:    f.measurementName,
+		LineProtocolsCount: f.lineProtocolsCount,
+		PointsPerCall:      f.pointsPerCall,
+		SkipCount:          f.skipCount,
+		Percentiles:        percentiles,
+		Quiet:              f.quiet,
+	}
+}
+
+// runConfig returns the settings of the run of a single writer, its Writer, Expected and Baseline are set by the run
+func (f flags) runConfig(percentiles []float64) bench.Config {
+	config := f.loadConfig(percentiles)
+	config.CountDelay = time.Duration(f.countDelaySeconds) * time.Second
+	if f.countStabilize {
+		config.CountStabilizeTimeout = time.Duration(f.countStabilizeTimeoutSeconds) * time.Second
+	}
+	config.CountTimeShards = f.countTimeShards
+	config.ReportSchedLatency = f.reportSchedLatency
+	config.ReportGoroutines = f.reportGoroutines
+	config.ReportAllocs = f.reportAllocs
+	config.ReportSyncOverhead = f.reportSyncOverhead
+	config.RampDownSeconds = f.rampDownSeconds
+	config.CheckpointInterval = time.Duration(f.checkpointCountInterval) * time.Second
+	config.ConcurrentReads = f.concurrentReads
+	config.TimelineBucket = time.Duration(f.throughputTimeline) * time.Second
+	config.MeasurementSwitchEvery = f.measurementSwitchEvery
+	config.Arrival = f.arrival
+	config.Model = f.model
+	config.ClientDelay = time.Duration(f.clientDelayMicros) * time.Microsecond
+	return config
+}
+
+// runOptions returns the options selecting how the writers are run
+func (f flags) runOptions() bench.RunOptions {
+	return bench.RunOptions{
+		Type:              f.writerType,
+		ClientType:        f.clientType(),
+		Repeat:            f.repeat,
+		Output:            f.output,
+		Urls:              f.endpoints(),
+		ClientPerWorker:   f.clientPerWorker,
+		RequireEmpty:      f.requireEmpty,
+		AppendMode:        f.appendMode,
+		VerifyOrder:       f.verifyOrder,
+		VerifyOrderSeries: f.verifyOrderSeries,
+		TraceBatches:      f.batchTraceOut != "",
+		KeepDeadLetters:   f.deadLetterFile != "",
+		PrintCountQuery:   f.printCountQuery,
+		Sweep: bench.BatchSweepConfig{
+			MinBatchSize:    f.sweepMinBatchSize,
+			MaxBatchSize:    f.sweepMaxBatchSize,
+			Factor:          f.sweepFactor,
+			Plateau:         f.sweepPlateau,
+			TargetErrorRate: f.targetErrorRate,
+		},
+	}
+}
+
+// parsePercentiles parses comma-separated percentiles like "50,90,99,99.9"
+func parsePercentiles(value string) ([]float64, error) {
+	var percentiles []float64
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		percentile, err := strconv.ParseFloat(item, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid percentile %q: %v", item, err)
+		}
+		if percentile <= 0 || percentile > 100 {
+			return nil, fmt.Errorf("percentile %v is out of range (0, 100]", percentile)
+		}
+		percentiles = append(percentiles, percentile)
+	}
+	return percentiles, nil
+}
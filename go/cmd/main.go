@@ -2,218 +2,516 @@ package main
 
 import (
 	"context"
-	"errors"
-	"flag"
 	"fmt"
-	"github.com/fatih/color"
 	"github.com/influxdata/influxdb-client-go"
+	"github.com/influxdata/influxdb-client-go/api/write"
 	_ "github.com/influxdata/influxdb1-client" // this is important because of the bug in go mod
-	client "github.com/influxdata/influxdb1-client/v2"
-	"strconv"
-	"sync"
+	"go-bechmark/pkg/bench"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strings"
 	"time"
 )
 
-type Writer interface {
-	Write(id int, measurementName string, iteration int)
-	Count(measurementName string) (int, error)
-	Close() error
-}
-
-type WriterV1 struct {
-	influx client.Client
-}
-
-type WriterV2 struct {
-	influx   influxdb2.InfluxDBClient
-	writeApi influxdb2.WriteApi
-}
-
-func NewWriterV2(client influxdb2.InfluxDBClient) *WriterV2 {
-	return &WriterV2{
-		influx:   client,
-		writeApi: client.WriteApi("my-org", "my-bucket"),
-	}
-}
+// version and commit of the tool, they are set at build time by -ldflags "-X main.version=... -X main.commit=..."
+var (
+	version = "dev"
+	commit  = "unknown"
+)
 
 //
 // https://pragmacoders.com/blog/multithreading-in-go-a-tutorial
 //
 func main() {
-	writerType := flag.String("type", "CLIENT_GO_V2", "Type of writer (default 'CLIENT_GO_V2'; CLIENT_GO_V1, CLIENT_GO_V2)")
-	threadsCount := flag.Int("threadsCount", 2000, "how much Thread use to write into InfluxDB")
-	secondsCount := flag.Int("secondsCount", 30, "how long write into InfluxDB")
-	batchSize := flag.Uint("batchSize", 1000, "batch size")
-	authToken := flag.String("token", "my-token", "InfluxDB 2 authentication token")
-	lineProtocolsCount := flag.Int("lineProtocolsCount", 100, "how much data writes in one batch")
-	skipCount := flag.Bool("skipCount", false, "skip counting count")
-	measurementName := flag.String("measurementName", fmt.Sprintf("sensor_%d", time.Now().UnixNano()), "writer measure destination")
-	flag.Parse()
-
-	expected := (*threadsCount) * (*secondsCount) * (*lineProtocolsCount)
-
-	blue := color.New(color.FgHiBlue).SprintFunc()
-	green := color.New(color.FgHiGreen).SprintFunc()
-	fmt.Println()
-	fmt.Printf("------------- %s -------------", blue(*writerType))
-	fmt.Println()
-	fmt.Println()
-	fmt.Println("measurement:        ", *measurementName)
-	fmt.Println("threadsCount:       ", *threadsCount)
-	fmt.Println("secondsCount:       ", *secondsCount)
-	fmt.Println("lineProtocolsCount: ", *lineProtocolsCount)
-	fmt.Println()
-	fmt.Println("expected size: ", expected)
-	fmt.Println()
-
-	var writer Writer
-	if *writerType == "CLIENT_GO_V2" {
-		influx := influxdb2.NewClientWithOptions("http://localhost:9999", *authToken, influxdb2.DefaultOptions().SetBatchSize(*batchSize))
-		writer = NewWriterV2(influx)
-	} else {
-		influx, err := client.NewHTTPClient(client.HTTPConfig{
-			Addr: "http://localhost:8086",
-		})
-		if err != nil {
-			panic(err)
-		}
-		writer = &WriterV1{
-			influx: influx,
+	f := parseFlags()
+
+	if f.printVersion {
+		fmt.Println(versionInfo())
+		os.Exit(0)
+	}
+
+	percentiles, err := parsePercentiles(f.percentilesList)
+	exitOnError(err)
+
+	if f.binaryA != "" || f.binaryB != "" {
+		if f.binaryA == "" || f.binaryB == "" {
+			exitOnError(fmt.Errorf("binaryA and binaryB have to be set together"))
 		}
+		labels, binaries := []string{"A", "B"}, []string{f.binaryA, f.binaryB}
+		summaries, err := runBinaries(labels, binaries, binaryArgs(f.extraHeaders), f.measurementName)
+		exitOnError(err)
+		printBinaries(labels, binaries, summaries)
+		return
 	}
 
-	stopExecution := make(chan bool)
-	var wg sync.WaitGroup
-	wg.Add(*threadsCount)
+	points, err := f.pointOptions()
+	exitOnError(err)
+	config, err := f.writerConfig(points)
+	exitOnError(err)
+	runConfig := f.runConfig(percentiles)
+	options := f.runOptions()
+	exitOnError(options.Validate(runConfig, config))
+	clientType := options.ClientType
 
-	start := time.Now()
+	// jsonOutput receives the JSON result, the text goes into stderr so that stdout is only the JSON
+	jsonOutput := os.Stdout
+	if f.output == "json" {
+		os.Stdout = os.Stderr
+	}
 
-	for i := 1; i <= *threadsCount; i++ {
-		go doLoad(&wg, stopExecution, i, *measurementName, *secondsCount, *lineProtocolsCount, writer)
+	expected := f.threadsCount * f.secondsCount * f.lineProtocolsCount
+	// timestamps of a series collapse into one point when they are truncated by timestampScale and precision
+	collapsed := expected
+	if f.measurementSwitchEvery == 0 {
+		distinct := points
+		distinct.DuplicateRate = 0
+		collapsed = distinct.UniqueKeys(f.threadsCount, f.lineProtocolsCount, (f.secondsCount+1)*f.lineProtocolsCount-1)
+	}
+	if !f.quiet {
+		printSettings(f, config, expected, collapsed)
 	}
 
-	go func() {
-		time.Sleep(time.Duration(*secondsCount) * time.Second)
-		fmt.Printf("\n\nThe time: %v seconds elapsed! Stopping all writers\n\n", *secondsCount)
-		close(stopExecution)
-	}()
+	if f.writerType == "SERIALIZE" {
+		serialize, err := bench.NewSerializer(f.serializeVersion, points)
+		exitOnError(err)
+		if !f.quiet {
+			fmt.Println("Serializing points with", blue(f.serializeVersion), "...")
+		}
+		count, size := bench.RunSerialize(f.threadsCount, f.secondsCount, f.measurementName, serialize)
+		printSerialized(count, size, f.secondsCount)
+		return
+	}
 
-	wg.Wait()
+	var replayMeasurement string
+	var replayLines []string
+	if f.writerType == "REPLAY" {
+		mapping, err := bench.ParseCsvMapping(f.replayMapping)
+		exitOnError(err)
+		replayMeasurement = mapping.Measurement
+		replayLines, err = bench.ReadCsv(f.replayCsv, mapping)
+		exitOnError(err)
+	}
 
-	if !*skipCount {
-		fmt.Println()
-		fmt.Println()
-		fmt.Println("Querying InfluxDB ...")
-		fmt.Println()
+	for _, warning := range options.Warnings(runConfig, config) {
+		warn(f.quiet, warning)
+	}
 
-		total, err := writer.Count(*measurementName)
-		if err != nil {
-			panic(err)
+	var batchTrace *os.File
+	if f.batchTraceOut != "" {
+		batchTrace, err = os.Create(f.batchTraceOut)
+		exitOnError(err)
+		config.BatchTrace = bench.NewBatchTrace(batchTrace)
+	}
+	var deadLetters *os.File
+	if f.deadLetterFile != "" {
+		deadLetters, err = os.Create(f.deadLetterFile)
+		exitOnError(err)
+		config.DeadLetters = bench.NewDeadLetters(deadLetters)
+	}
+	stopTracing := func() {}
+	if f.otelEndpoint != "" {
+		shutdown, err := bench.StartTracing(f.otelEndpoint)
+		exitOnError(err)
+		stopTracing = func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := shutdown(ctx); err != nil {
+				fmt.Println("Warning: exporting of the spans failed:", err)
+			}
 		}
-		fmt.Println("Results:")
-		fmt.Println("-> expected:        ", expected)
-		fmt.Println("-> total:           ", total)
-		fmt.Println("-> rate [%]:        ", (float64(total)/float64(expected))*100)
-		fmt.Println("-> rate [msg/sec]:  ", green(total / *secondsCount))
-		fmt.Println()
-		fmt.Println("Total time:", time.Since(start))
 	}
 
-	if err := writer.Close(); err != nil {
-		panic(err)
+	if f.printCountQuery {
+		printCountQueries(f, config)
+		return
+	}
+	if f.checkClockSkew && (f.skipHealthCheck || clientType == "HTTP_SINK" || clientType == "SELFTEST") {
+		warn(f.quiet, "checkClockSkew is supported only by the health check of InfluxDB")
+	}
+	if !f.skipHealthCheck && clientType != "HTTP_SINK" && clientType != "SELFTEST" {
+		checkHealth(f)
 	}
-}
 
-func doLoad(wg *sync.WaitGroup, stopExecution <-chan bool, id int, measurementName string, secondsCount int, lineProtocolsCount int, influx Writer) {
-	defer wg.Done()
+	goroutinesBefore := runtime.NumGoroutine()
+	// finish stops the tracing and reports leaked goroutines after the writers were closed
+	finish := func() {
+		stopTracing()
+		if f.checkLeaks {
+			reportLeaks(goroutinesBefore)
+		}
+	}
 
-	for i := 1; i <= secondsCount; i++ {
-		select {
-		case <-stopExecution:
-			return
-		default:
+	switch f.writerType {
+	case "COMPARE_V2":
+		results, err := bench.RunCompareV2(f.firstUrl(), config, f.loadConfig(nil))
+		exitOnError(err)
+		printCompareV2(results, f.pointsPerCall, percentiles, f.skipCount)
+		if f.output == "markdown" {
+			printMarkdownCompare(results, f.pointsPerCall, percentiles, f.skipCount)
+		}
+		finish()
+		return
+	case "SELFTEST":
+		load := f.loadConfig(nil)
+		load.ThreadsCount, load.SecondsCount = 10, 2
+		if !printSelfTest(bench.RunSelfTest(config, load)) {
+			os.Exit(1)
+		}
+		return
+	case "COUNT_CHECK":
+		check, err := bench.RunCountCheck(config, f.loadConfig(nil))
+		exitOnError(err)
+		if !printCountCheck(check, f.countLang) {
+			os.Exit(1)
+		}
+		return
+	case "ALL":
+		results, err := bench.RunAll(bench.AllWriterTypes, config, f.loadConfig(percentiles))
+		exitOnError(err)
+		printRanking(results, f.pointsPerCall)
+		if f.output == "markdown" {
+			printMarkdownRanking(results, f.pointsPerCall, percentiles, f.skipCount)
+		}
+		finish()
+		return
+	}
 
-			if id == 1 {
-				fmt.Printf("\rwriting iterations: %v/%v", i, secondsCount)
-			}
+	newWriter := writerFactory(f)
 
-			start := i * lineProtocolsCount
-			end := start + lineProtocolsCount
-			for j := start; j < end; j++ {
-				select {
-				case <-stopExecution:
-					return
-				default:
-					influx.Write(id, measurementName, j)
-				}
+	if f.repeat > 1 {
+		results, err := bench.RunRepeated(newWriter, config, f.loadConfig(percentiles), f.repeat)
+		exitOnError(err)
+		summary := bench.SummarizeRepeated(results, f.pointsPerCall, percentiles)
+		printRepeated(results, summary, f.pointsPerCall, percentiles)
+		if f.output == "markdown" {
+			printMarkdownRepeated(summary, percentiles)
+		}
+		finish()
+		return
+	}
+
+	if f.writerType == "BATCH_SWEEP" {
+		if !f.quiet {
+			fmt.Println("Sweeping batch sizes of", blue(clientType), "...")
+			fmt.Println()
+		}
+		result, err := bench.RunBatchSweep(newWriter, config, f.loadConfig(nil), options.Sweep)
+		exitOnError(err)
+		printBatchSweep(result)
+		if f.output == "markdown" {
+			printMarkdownBatchSweep(result)
+		}
+		finish()
+		return
+	}
+
+	writer := newWriter(config)
+
+	if f.writerType == "REPLAY" {
+		if !f.quiet {
+			fmt.Println("Replaying", len(replayLines), "rows of", f.replayCsv, "by", blue(clientType), "...")
+			fmt.Println()
+		}
+		result, err := bench.RunReplay(writer, replayLines, int(f.batchSize), f.threadsCount)
+		exitOnError(err)
+		printReplay(result)
+		if !f.skipCount {
+			total, err := writer.Count(replayMeasurement)
+			if err != nil {
+				fmt.Println("-> count failed:    ", err)
+			} else {
+				fmt.Println("-> total:           ", total)
 			}
-			time.Sleep(time.Duration(1) * time.Second)
 		}
+		closeErr := writer.Close()
+		finish()
+		handleCloseError(closeErr, f.ignoreCloseError)
+		return
 	}
-}
 
-func (p *WriterV2) Write(id int, measurementName string, iteration int) {
-	point := influxdb2.NewPoint(
-		measurementName,
-		map[string]string{"id": fmt.Sprintf("%v", id)},
-		map[string]interface{}{"temperature": fmt.Sprintf("%v", time.Now().UnixNano())},
-		time.Unix(0, int64(iteration)))
+	if f.writerType == "AUTOTUNE" {
+		if !f.quiet {
+			fmt.Println("Tuning concurrency of", blue(clientType), "...")
+			fmt.Println()
+		}
+		result, err := bench.RunAutotune(writer, bench.AutotuneConfig{
+			MaxWorkers:         f.threadsCount,
+			SecondsCount:       f.secondsCount,
+			LineProtocolsCount: f.lineProtocolsCount,
+			PointsPerCall:      f.pointsPerCall,
+			MeasurementName:    f.measurementName,
+			Step:               f.autotuneStep,
+			TargetP99:          time.Duration(f.targetP99Millis * float64(time.Millisecond)),
+			TargetErrorRate:    f.targetErrorRate,
+			Quiet:              f.quiet,
+		})
+		exitOnError(err)
+		printAutotune(result)
+		closeErr := writer.Close()
+		finish()
+		handleCloseError(closeErr, f.ignoreCloseError)
+		return
+	}
 
-	p.writeApi.WritePoint(point)
-}
+	runConfig.Writer = writer
+	runConfig.Expected = collapsed
+	if f.requireEmpty || f.appendMode {
+		runConfig.Baseline = countBaseline(f, writer)
+	}
 
-func (p *WriterV2) Count(measurementName string) (int, error) {
-	query := `from(bucket:"my-bucket") 
-		|> range(start: 0, stop: now()) 
-		|> filter(fn: (r) => r._measurement == "` + measurementName + `") 
-		|> pivot(rowKey:["_time"], columnKey: ["_field"], valueColumn: "_value")
-		|> drop(columns: ["id", "host"])
-		|> count(column: "temperature")`
+	cpuStart, cpuAvailable := processCPUTime()
+	result, err := bench.Run(runConfig)
+	exitOnError(err)
+	cpuEnd, _ := processCPUTime()
+	var tracedBatches int
+	if batchTrace != nil {
+		tracedBatches, err = config.BatchTrace.Flush()
+		exitOnError(err)
+		exitOnError(batchTrace.Close())
+	}
 
-	queryResult, err := p.influx.QueryApi("my-org").Query(context.Background(), query)
-	if err != nil {
-		return 0, err
+	printLoad(f, result)
+	if f.latencyDump != "" {
+		exitOnError(dumpLatencies(f.latencyDump, result.Latencies))
+		fmt.Printf("-> %v samples dumped into %s\n", len(result.Samples), f.latencyDump)
+	}
+	if batchTrace != nil {
+		fmt.Printf("-> %v batches traced into %s\n", tracedBatches, f.batchTraceOut)
 	}
-	total := 0
-	if !queryResult.Next() {
-		if queryResult.Err() != nil {
-			return 0, queryResult.Err()
+	printDetails(f, result, cpuEnd-cpuStart, cpuAvailable)
+	if result.Counted {
+		printCounts(f, config, writer, result, expected)
+	}
+	if f.verifyOrder {
+		printOrderReport(bench.VerifyOrder(writer, f.measurementName, f.threadsCount, f.verifyOrderSeries))
+	}
+
+	// closing sends the points still buffered by the writer, it takes long for large batches and slow servers
+	closeStart := time.Now()
+	closeErr := writer.Close()
+	closeTime := time.Since(closeStart)
+	stopTracing()
+	var deadBatches, deadPoints int
+	if deadLetters != nil {
+		// the writer adds the points that failed to be flushed when it is closed
+		deadBatches, deadPoints, err = config.DeadLetters.Flush()
+		exitOnError(err)
+		exitOnError(deadLetters.Close())
+	}
+	printClose(f, config, result, closeTime, deadBatches, deadPoints)
+
+	summary := newRunSummary(f.label, clientType, f.threadsCount, f.secondsCount, f.lineProtocolsCount, f.pointsPerCall, result)
+	if f.resultJson != "" {
+		if err := writeRunSummary(f.resultJson, summary); err != nil {
+			fmt.Println("Warning: writing of the JSON result failed:", err)
+		}
+	}
+	if f.bundleOut != "" {
+		var latencies [][]time.Duration
+		if f.bundleLatencies {
+			latencies = result.Latencies
+		}
+		if err := writeBundle(f.bundleOut, summary, latencies); err != nil {
+			fmt.Println("Warning: writing of the bundle failed:", err)
 		} else {
-			return 0, errors.New("unknown error")
+			fmt.Println()
+			fmt.Println("Bundle written into", f.bundleOut)
+		}
+	}
+	if f.output == "markdown" {
+		printMarkdownResult(clientType, f.threadsCount, f.secondsCount, f.pointsPerCall, result)
+	}
+	if f.output == "json" {
+		exitOnError(printRunSummary(jsonOutput, summary))
+	}
+
+	if f.checkLeaks {
+		reportLeaks(goroutinesBefore)
+	}
+
+	if f.selfReport {
+		reportUrl := ""
+		if strings.HasSuffix(clientType, "_V2") {
+			reportUrl = f.firstUrl()
+		}
+		if err := writeSelfReport(reportUrl, f.authToken, f.org, f.selfReportBucket, selfReportPoint(f, config, result)); err != nil {
+			fmt.Println()
+			fmt.Println("Warning: writing of the self report failed:", err)
+		} else if !f.quiet {
+			fmt.Println()
+			fmt.Printf("Self report written into %s/%s\n", f.selfReportBucket, f.selfReportMeasurement)
 		}
-	} else {
-		total = int(queryResult.Record().ValueByKey("temperature").(int64))
 	}
-	return total, nil
+
+	handleCloseError(closeErr, f.ignoreCloseError)
 }
-func (p *WriterV2) Close() error {
-	p.influx.Close()
-	return nil
+
+// exitOnError exits with non-zero status after printing the error, it does nothing for nil
+func exitOnError(err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
 }
 
-func (p *WriterV1) Write(id int, measurementName string, iteration int) {
+// writerFactory returns the constructor of the writers of the client type, the threads get their own writers
+// with clientPerWorker and the urls are written by a writer of every endpoint
+func writerFactory(f flags) func(config bench.WriterConfig) bench.Writer {
+	clientType := f.clientType()
+	return func(config bench.WriterConfig) bench.Writer {
+		if f.clientPerWorker {
+			writers := make([]bench.Writer, f.threadsCount)
+			for i := range writers {
+				writers[i] = bench.NewWriter(clientType, "", config)
+			}
+			return bench.NewWriterPerWorker(writers)
+		}
+		endpoints := f.endpoints()
+		if len(endpoints) == 0 {
+			serverUrl := ""
+			if clientType == "HTTP_SINK" {
+				serverUrl = f.sinkUrl
+			}
+			return bench.NewWriter(clientType, serverUrl, config)
+		}
+		writers := make([]bench.Writer, len(endpoints))
+		for i, endpoint := range endpoints {
+			writers[i] = bench.NewWriter(clientType, endpoint, config)
+		}
+		return bench.NewWriterMulti(endpoints, writers)
+	}
+}
 
-	bp, _ := client.NewBatchPoints(client.BatchPointsConfig{
-		Database: "iot_writes",
+// printCountQueries prints the queries counting the points of the measurements of the run
+func printCountQueries(f flags, config bench.WriterConfig) {
+	queryWriter := bench.NewWriter(f.clientType(), f.firstUrl(), config)
+	measurements := []string{f.measurementName}
+	if f.measurementSwitchEvery > 0 {
+		measurements = append(measurements, bench.AlternateMeasurement(f.measurementName))
+	}
+	for _, measurement := range measurements {
+		query, err := bench.CountQuery(queryWriter, measurement)
+		exitOnError(err)
+		fmt.Println(query)
+	}
+	_ = queryWriter.Close()
+}
+
+// checkHealth exits unless InfluxDB of the writers is up, it prints the skew of the clock of the server by checkClockSkew
+func checkHealth(f flags) {
+	healthTypes := []string{f.clientType()}
+	endpoints := []string{""}
+	switch f.writerType {
+	case "COMPARE_V2":
+		healthTypes = []string{"CLIENT_GO_V2"}
+	case "ALL", "COUNT_CHECK":
+		// ALL and COUNT_CHECK write into the default V1 and V2 servers
+		healthTypes = []string{"CLIENT_GO_V1", "CLIENT_GO_V2"}
+	default:
+		if f.urls != "" {
+			endpoints = f.endpoints()
+		}
+	}
+	for _, healthType := range healthTypes {
+		for _, endpoint := range endpoints {
+			if err := bench.CheckHealth(healthType, endpoint); err != nil {
+				fmt.Fprintln(os.Stderr, "Error: InfluxDB is not available:", err)
+				fmt.Fprintln(os.Stderr, "Start the server or use -skipHealthCheck to run anyway")
+				os.Exit(1)
+			}
+			if f.checkClockSkew {
+				skew, err := bench.ClockSkew(healthType, endpoint)
+				exitOnError(err)
+				fmt.Printf("clock skew of %v: %v\n", bench.ServerUrl(healthType, endpoint), skew)
+				if (skew > bench.ClockSkewWarning || skew < -bench.ClockSkewWarning) && !f.quiet {
+					fmt.Printf("%s the clock of the server differs by more than %v, now() of the count queries and -countRange window don't match the written timestamps\n\n", red("Warning:"), bench.ClockSkewWarning)
+				}
+			}
+		}
+	}
+}
+
+// countBaseline counts the points of the measurements before the run, it exits when requireEmpty finds any,
+// the baseline is the count of appendMode and zero otherwise
+func countBaseline(f flags, writer bench.Writer) int {
+	existing, err := bench.CountExisting(bench.Config{
+		Writer:                 writer,
+		MeasurementName:        f.measurementName,
+		MeasurementSwitchEvery: f.measurementSwitchEvery,
+		CountTimeShards:        f.countTimeShards,
 	})
+	exitOnError(err)
+	if !f.quiet {
+		fmt.Printf("Existing points of %s: %v\n", f.measurementName, existing)
+		fmt.Println()
+	}
+	if existing > 0 && f.requireEmpty {
+		fmt.Fprintf(os.Stderr, "Error: the measurement %s is not empty, it has %v points\n", f.measurementName, existing)
+		fmt.Fprintln(os.Stderr, "Use another -measurementName, or -appendMode to subtract them from the total")
+		_ = writer.Close()
+		os.Exit(1)
+	}
+	if f.appendMode {
+		return existing
+	}
+	return 0
+}
+
+// handleCloseError reports the error of closing the writer, it exits with non-zero status unless the error is ignored
+func handleCloseError(closeErr error, ignore bool) {
+	if closeErr == nil {
+		return
+	}
+	if ignore {
+		fmt.Println()
+		fmt.Println("Warning: closing of the writer failed:", closeErr)
+	} else {
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Error: closing of the writer failed:", closeErr)
+		os.Exit(1)
+	}
+}
 
-	tags := map[string]string{"id": fmt.Sprintf("%v", id)}
-	fields := map[string]interface{}{
-		"temperature": fmt.Sprintf("%v", time.Now().UnixNano()),
+// writeSelfReport writes the summary point of the run by the V2 client, the default V2 URL is used for empty serverUrl
+func writeSelfReport(serverUrl string, authToken string, org string, bucket string, point *write.Point) error {
+	if serverUrl == "" {
+		serverUrl = "http://localhost:9999"
 	}
-	pt, _ := client.NewPoint(measurementName, tags, fields, time.Unix(0, int64(iteration)))
-	bp.AddPoint(pt)
-	if err := p.influx.Write(bp); err != nil {
+	influx := influxdb2.NewClient(strings.TrimSuffix(serverUrl, "/"), authToken)
+	defer influx.Close()
+	return influx.WriteApiBlocking(org, bucket).WritePoint(context.Background(), point)
+}
 
+// versionInfo returns the tool version, git commit and versions of the InfluxDB clients it was built against
+func versionInfo() string {
+	info := fmt.Sprintf("version: %s\ncommit:  %s", version, commit)
+	clients := clientVersions()
+	if clients == nil {
+		return info + "\nbuild info is not available"
+	}
+	for _, client := range clients {
+		info += "\n" + client
 	}
+	return info
 }
-func (p *WriterV1) Count(measurementName string) (int, error) {
-	q := client.NewQuery("SELECT count(*) FROM "+measurementName, "iot_writes", "")
-	if response, err := p.influx.Query(q); err == nil && response.Error() == nil {
-		count := response.Results[0].Series[0].Values[0][1]
-		i, err := strconv.Atoi(fmt.Sprintf("%v", count))
-		return i, err
-	}
-	return 0, nil
+
+// clientVersions returns the InfluxDB clients the binary is built with like "github.com/influxdata/influxdb1-client v0.0.0-...",
+// it returns nil when the build info is not available
+func clientVersions() []string {
+	buildInfo, ok := debug.ReadBuildInfo()
+	if !ok {
+		return nil
+	}
+	clients := []string{}
+	for _, module := range buildInfo.Deps {
+		switch module.Path {
+		case "github.com/influxdata/influxdb-client-go", "github.com/influxdata/influxdb1-client":
+			if module.Replace != nil {
+				module = module.Replace
+			}
+			clients = append(clients, fmt.Sprintf("%s %s", module.Path, module.Version))
+		}
+	}
+	return clients
 }
-func (p *WriterV1) Close() error { return p.influx.Close() }
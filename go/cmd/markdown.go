@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"go-bechmark/pkg/bench"
+	"strings"
+	"time"
+)
+
+// markdownRow prints cells as a row of a GitHub-flavored Markdown table
+func markdownRow(cells ...interface{}) {
+	values := make([]string, len(cells))
+	for i, cell := range cells {
+		values[i] = strings.Replace(fmt.Sprint(cell), "|", "\\|", -1)
+	}
+	fmt.Println("| " + strings.Join(values, " | ") + " |")
+}
+
+// markdownHeader prints the header row of a Markdown table with its delimiter row
+func markdownHeader(columns ...interface{}) {
+	markdownRow(columns...)
+	delimiters := make([]interface{}, len(columns))
+	for i := range delimiters {
+		delimiters[i] = "---"
+	}
+	markdownRow(delimiters...)
+}
+
+// printMarkdownResult prints the result of the run as a Markdown table
+func printMarkdownResult(writerType string, threadsCount int, secondsCount int, pointsPerCall int, result bench.Result) {
+	columns := []interface{}{"writer", "threads", "seconds", "rate [points/sec]", "expected"}
+	if result.Counted {
+		columns = append(columns, "total", "rate [%]")
+	}
+	for _, percentile := range result.Percentiles {
+		columns = append(columns, fmt.Sprintf("p%v latency", percentile.Percentile))
+	}
+	throughput := float64(len(result.Samples)*pointsPerCall) / result.Elapsed.Seconds()
+	cells := []interface{}{writerType, threadsCount, secondsCount, fmt.Sprintf("%.1f", throughput), result.Expected}
+	if result.Counted {
+		cells = append(cells, result.Total, fmt.Sprintf("%.1f", result.Rate))
+	}
+	for _, percentile := range result.Percentiles {
+		cells = append(cells, percentile.Latency)
+	}
+	fmt.Println()
+	markdownHeader(columns...)
+	markdownRow(cells...)
+}
+
+// printMarkdownCompare prints the async and sync writers of the COMPARE_V2 type as a Markdown table
+func printMarkdownCompare(results []bench.CompareResult, pointsPerCall int, percentiles []float64, skipCount bool) {
+	columns := []interface{}{"writer", "rate [points/sec]", "failed writes"}
+	if !skipCount {
+		columns = append(columns, "total")
+	}
+	for _, percentile := range percentiles {
+		columns = append(columns, fmt.Sprintf("p%v latency", percentile))
+	}
+	fmt.Println()
+	markdownHeader(columns...)
+	for _, result := range results {
+		cells := []interface{}{result.Name, fmt.Sprintf("%.1f", result.Throughput(pointsPerCall)), result.Failed}
+		if !skipCount {
+			cells = append(cells, result.Total)
+		}
+		for _, percentile := range percentiles {
+			if len(result.Samples) == 0 {
+				cells = append(cells, "-")
+			} else {
+				cells = append(cells, bench.LatencyPercentile(result.Samples, percentile))
+			}
+		}
+		markdownRow(cells...)
+	}
+}
+
+// printMarkdownRepeated prints the mean and standard deviation of repetitions as a Markdown table
+func printMarkdownRepeated(summary bench.RepeatSummary, percentiles []float64) {
+	fmt.Println()
+	markdownHeader("metric", "mean", "stddev")
+	markdownRow("rate [points/sec]", fmt.Sprintf("%.1f", summary.Throughput.Mean), fmt.Sprintf("%.1f", summary.Throughput.Stddev))
+	for i, percentile := range percentiles {
+		latency := summary.Percentiles[i]
+		markdownRow(fmt.Sprintf("p%v latency", percentile), time.Duration(latency.Mean), time.Duration(latency.Stddev))
+	}
+	if summary.Loss != nil {
+		markdownRow("lost points [%]", fmt.Sprintf("%.2f", summary.Loss.Mean), fmt.Sprintf("%.2f", summary.Loss.Stddev))
+	}
+	if summary.FailedRequests != nil {
+		markdownRow("failed requests [%]", fmt.Sprintf("%.2f", summary.FailedRequests.Mean), fmt.Sprintf("%.2f", summary.FailedRequests.Stddev))
+	}
+}
+
+// printMarkdownBatchSweep prints the runs of the BATCH_SWEEP type as a Markdown table
+func printMarkdownBatchSweep(result bench.BatchSweepResult) {
+	fmt.Println()
+	markdownHeader("batch size", "rate [points/sec]", "error rate", "recommended")
+	for _, step := range result.Steps {
+		recommended := ""
+		if step.BatchSize == result.Recommended {
+			recommended = "yes"
+		}
+		markdownRow(step.BatchSize, fmt.Sprintf("%.1f", step.Throughput), fmt.Sprintf("%.4f", step.ErrorRate), recommended)
+	}
+}
+
+// printMarkdownRanking prints writers of the ALL type ranked by throughput as a Markdown table
+func printMarkdownRanking(results []bench.RankResult, pointsPerCall int, percentiles []float64, skipCount bool) {
+	columns := []interface{}{"#", "writer", "rate [points/sec]", "relative [%]"}
+	if !skipCount {
+		columns = append(columns, "total", "rate [%]")
+	}
+	for _, percentile := range percentiles {
+		columns = append(columns, fmt.Sprintf("p%v latency", percentile))
+	}
+	fmt.Println()
+	markdownHeader(columns...)
+	fastest := results[0].Throughput(pointsPerCall)
+	for i, result := range results {
+		throughput := result.Throughput(pointsPerCall)
+		relative := 0.0
+		if fastest > 0 {
+			relative = throughput / fastest * 100
+		}
+		cells := []interface{}{i + 1, result.WriterType, fmt.Sprintf("%.1f", throughput), fmt.Sprintf("%.1f", relative)}
+		if !skipCount {
+			cells = append(cells, result.Result.Total, fmt.Sprintf("%.1f", result.Result.Rate))
+		}
+		for j := range percentiles {
+			if j < len(result.Result.Percentiles) {
+				cells = append(cells, result.Result.Percentiles[j].Latency)
+			} else {
+				cells = append(cells, "-")
+			}
+		}
+		markdownRow(cells...)
+	}
+}
@@ -0,0 +1,793 @@
+package main
+
+import (
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/influxdata/influxdb-client-go"
+	"github.com/influxdata/influxdb-client-go/api/write"
+	"go-bechmark/pkg/bench"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+	"time"
+)
+
+// blue, green and red highlight the output
+var (
+	blue  = color.New(color.FgHiBlue).SprintFunc()
+	green = color.New(color.FgHiGreen).SprintFunc()
+	red   = color.New(color.FgHiRed).SprintFunc()
+)
+
+// warn prints the warning about the configuration followed by an empty line unless the output is quiet
+func warn(quiet bool, message string) {
+	if quiet {
+		return
+	}
+	fmt.Println("Warning:", message)
+	fmt.Println()
+}
+
+// printSettings prints the settings of the run and the expected count of points
+func printSettings(f flags, config bench.WriterConfig, expected int, collapsed int) {
+	fmt.Println()
+	fmt.Printf("------------- %s -------------", blue(f.writerType))
+	fmt.Println()
+	fmt.Println()
+	fmt.Println(versionInfo())
+	fmt.Println()
+	if f.label != "" {
+		fmt.Println("label:              ", f.label)
+	}
+	fmt.Println("measurement:        ", f.measurementName)
+	if f.measurementSwitchEvery > 0 {
+		fmt.Printf("-> switched to %s every %v points\n", bench.AlternateMeasurement(f.measurementName), f.measurementSwitchEvery)
+	}
+	fmt.Println("threadsCount:       ", f.threadsCount)
+	fmt.Println("secondsCount:       ", f.secondsCount)
+	fmt.Println("lineProtocolsCount: ", f.lineProtocolsCount)
+	fmt.Println("pointsPerCall:      ", f.pointsPerCall)
+	if f.arrival != "uniform" {
+		fmt.Println("arrival:            ", f.arrival)
+	}
+	if f.model != "burst" {
+		fmt.Println("model:              ", f.model)
+	}
+	if f.clientDelayMicros > 0 {
+		fmt.Println("clientDelay:        ", time.Duration(f.clientDelayMicros)*time.Microsecond)
+	}
+	if f.rampDownSeconds > 0 {
+		fmt.Println("rampDownSeconds:    ", f.rampDownSeconds)
+	}
+	fmt.Println("paddingBytes:       ", f.paddingBytes)
+	fmt.Println("timestampScale:     ", f.timestampScale)
+	fmt.Println("interleaveSeries:   ", f.interleaveSeries)
+	fmt.Println("orderedTimestamps:  ", f.orderedTimestamps)
+	if f.writerType == "CLIENT_GO_V2" || f.writerType == "COMPARE_V2" {
+		fmt.Println("v2WriteMode:        ", f.v2WriteMode)
+	}
+	if f.intFields {
+		fmt.Println("intFields:          ", f.intFields)
+	}
+	if f.uintFields {
+		fmt.Println("uintFields:         ", f.uintFields)
+	}
+	if f.boolFields {
+		fmt.Println("boolFields:         ", f.boolFields)
+	}
+	if f.fieldSpec != "" {
+		fmt.Println("fieldSpec:          ", f.fieldSpec)
+	}
+	if f.stringFieldValues != "" {
+		fmt.Println("stringFieldValues:  ", f.stringFieldValues)
+	}
+	if f.valueExpr != "" {
+		fmt.Println("valueExpr:          ", f.valueExpr)
+	}
+	if f.duplicateRate > 0 {
+		fmt.Println("duplicateRate:      ", f.duplicateRate)
+	}
+	if f.fieldKeyChurn > 0 {
+		fmt.Println("fieldKeyChurn:      ", f.fieldKeyChurn)
+	}
+	if f.urls != "" {
+		fmt.Println("urls:               ", f.urls)
+	}
+	if f.hostHeader != "" {
+		dialed := f.urls
+		switch {
+		case f.unixSocket != "":
+			dialed = f.unixSocket
+		case dialed == "" && f.writerType == "HTTP_SINK":
+			dialed = bench.ServerUrl(f.writerType, f.sinkUrl)
+		case dialed == "":
+			dialed = bench.ServerUrl(f.writerType, "")
+		}
+		fmt.Printf("hostHeader:          %s (connecting to %s)\n", f.hostHeader, dialed)
+	}
+	if f.cloud {
+		fmt.Println("cloud org ID:       ", f.org)
+	}
+	if f.clientPerWorker {
+		fmt.Println("clientPerWorker:    ", f.clientPerWorker)
+	}
+	if f.unixSocket != "" {
+		fmt.Println("unixSocket:         ", f.unixSocket)
+	}
+	if f.writerType == "UDP_V1" {
+		fmt.Println("udpPayloadBytes:    ", f.udpPayloadBytes)
+	}
+	if f.writeConsistency != "" {
+		fmt.Println("writeConsistency:   ", f.writeConsistency)
+	}
+	if strings.HasPrefix(f.writerType, "HTTP_") {
+		fmt.Println("tcpNoDelay:         ", f.tcpNoDelay)
+		fmt.Println("contentType:        ", f.contentType)
+		fmt.Printf("lineSeparator:       %q\n", config.LineSeparator)
+	}
+	if config.Points.RunTag != "" {
+		fmt.Println("run tag:            ", config.Points.RunTag)
+	}
+	if f.retryBudget > 0 {
+		fmt.Println("retryBudget:        ", f.retryBudget)
+	}
+	if f.reusePoints {
+		fmt.Println("reusePoints:         true, the fields of every series are static")
+	}
+	if len(f.extraHeaders) > 0 {
+		fmt.Println("headers:            ", f.extraHeaders.String())
+	}
+	if f.precision != "ns" {
+		fmt.Println("precision:          ", f.precision)
+	}
+	if f.floatFormat != "" {
+		fmt.Println("floatFormat:        ", f.floatFormat)
+	}
+	if f.deadLetterFile != "" {
+		fmt.Println("deadLetterFile:     ", f.deadLetterFile)
+	}
+	fmt.Println()
+	fmt.Println("expected size: ", expected)
+	if collapsed < expected {
+		fmt.Printf("%s timestamps truncated by timestampScale %v and precision %v collapse %v points into others, expected size is %v\n", red("Warning:"), f.timestampScale, f.precision, expected-collapsed, collapsed)
+	}
+	fmt.Println()
+}
+
+// printSerialized prints the points serialized by the SERIALIZE type
+func printSerialized(count int, size int, secondsCount int) {
+	fmt.Println()
+	fmt.Println("Results:")
+	fmt.Println("-> serialized:       ", count)
+	fmt.Println("-> rate [msg/sec]:   ", green(count/secondsCount))
+	fmt.Println("-> rate [bytes/sec]: ", size/secondsCount)
+}
+
+// printSelfTest prints the outcome of every writer of the SELFTEST type, it returns false when any of them failed
+func printSelfTest(results []bench.SelfTestResult) bool {
+	fmt.Println()
+	fmt.Println("Self test:")
+	ok := true
+	for _, result := range results {
+		if result.Ok() {
+			fmt.Printf("-> %-13v %s, %v of %v points\n", result.WriterType, green("PASS"), result.Total, result.Expected)
+			continue
+		}
+		ok = false
+		if result.Err != nil {
+			fmt.Printf("-> %-13v %s, %v\n", result.WriterType, red("FAIL"), result.Err)
+		} else {
+			fmt.Printf("-> %-13v %s, %v of %v points\n", result.WriterType, red("FAIL"), result.Total, result.Expected)
+		}
+	}
+	return ok
+}
+
+// printCountCheck prints the counts of the COUNT_CHECK type, it returns false when they differ
+func printCountCheck(check bench.CountCheck, countLang string) bool {
+	fmt.Println()
+	fmt.Println("Count check:")
+	fmt.Println("-> expected:        ", check.Expected)
+	fmt.Printf("-> CLIENT_GO_V1:     %v (InfluxQL), rate [%%]: %.2f\n", check.V1.Total, check.V1.Rate)
+	fmt.Printf("-> CLIENT_GO_V2:     %v (%s), rate [%%]: %.2f\n", check.V2.Total, countLang, check.V2.Rate)
+	if !check.Agree() {
+		fmt.Println("->", red("the counts differ"), "by", check.V1.Total-check.V2.Total)
+		return false
+	}
+	fmt.Println("->", green("the counts agree"))
+	return true
+}
+
+// printBatchSweep prints the runs of the batch sizes of the BATCH_SWEEP type and the recommended batch size
+func printBatchSweep(result bench.BatchSweepResult) {
+	fmt.Println("Batch sizes:")
+	for _, step := range result.Steps {
+		fmt.Printf("-> %8v: rate [points/sec]: %10.1f, error rate: %.4f\n", step.BatchSize, step.Throughput, step.ErrorRate)
+	}
+	fmt.Println("-> stopped:", result.Stopped)
+	if result.Recommended == 0 {
+		fmt.Println("-> no batch size within the target error rate")
+	} else {
+		fmt.Println("-> recommended batch size:", green(result.Recommended))
+	}
+}
+
+// printReplay prints the replayed lines of the REPLAY type
+func printReplay(result bench.ReplayResult) {
+	fmt.Println("Results:")
+	fmt.Println("-> replayed lines:  ", result.Lines)
+	fmt.Println("-> failed batches:  ", result.Failed, "of", result.Batches)
+	fmt.Println("-> rate [msg/sec]:  ", green(int64(float64(result.Lines)/result.Elapsed.Seconds())))
+	fmt.Println("-> replay time:     ", result.Elapsed)
+}
+
+// printAutotune prints the concurrency the AUTOTUNE type converged to
+func printAutotune(result bench.AutotuneResult) {
+	fmt.Println()
+	fmt.Println("Results:")
+	if result.BestWorkers == 0 {
+		fmt.Println("-> no stable second within the targets")
+	} else {
+		fmt.Println("-> converged concurrency: ", result.BestWorkers)
+		fmt.Println("-> rate [msg/sec]:        ", result.BestThroughput)
+	}
+}
+
+// printLoad prints the writes of the endpoints, the write latency and the throughput of the run
+func printLoad(f flags, result bench.Result) {
+	if !f.quiet {
+		fmt.Println()
+		fmt.Println()
+	}
+	if len(result.Endpoints) > 0 {
+		fmt.Println("Endpoints:")
+		for _, endpoint := range result.Endpoints {
+			fmt.Printf("-> %s: %v writes, rate [msg/sec]: %v\n", endpoint.Url, endpoint.Writes, green(endpoint.Writes/int64(f.secondsCount)))
+		}
+		fmt.Println()
+	}
+
+	fmt.Println("Write latency:")
+	printLatencies(result)
+	if result.Elapsed > 0 {
+		fmt.Printf("-> throughput [points/sec]: %v (%s model)\n", green(int64(float64(result.Attempted)/result.Elapsed.Seconds())), f.model)
+	}
+}
+
+// printDetails prints the optional measurements of the run, cpu is the CPU time of the process during the run
+func printDetails(f flags, result bench.Result, cpu time.Duration, cpuAvailable bool) {
+	printConnectionSetups(result.ConnectionSetups)
+
+	if result.Rotation != nil {
+		fmt.Println()
+		fmt.Println("Token rotation:")
+		fmt.Println("-> rotations:       ", result.Rotation.Rotations)
+		fmt.Println("-> write errors:    ", result.Rotation.Errors)
+		fmt.Println("-> around rotation: ", result.Rotation.ErrorsAroundRotation)
+		if result.Rotation.LastError != nil {
+			fmt.Println("-> last error:      ", result.Rotation.LastError)
+		}
+	}
+
+	if len(result.Timeline) > 0 {
+		printTimeline(result.Timeline, time.Duration(f.throughputTimeline)*time.Second)
+	}
+
+	if sizes := result.BatchSizes; sizes != nil {
+		fmt.Println()
+		fmt.Println("Batch sizes:")
+		fmt.Println("-> batches:         ", sizes.Batches)
+		fmt.Printf("-> min/avg/max:      %v/%.1f/%v\n", sizes.Min, sizes.Avg, sizes.Max)
+		fmt.Printf("-> p50/p90/p99:      %v/%v/%v\n", sizes.P50, sizes.P90, sizes.P99)
+	}
+
+	if reads := result.Reads; reads != nil {
+		fmt.Println()
+		fmt.Printf("Concurrent reads (%v readers):\n", reads.Readers)
+		fmt.Println("-> queries:         ", reads.Queries)
+		fmt.Println("-> failed queries:  ", reads.Errors)
+		fmt.Printf("-> throughput [queries/sec]: %.1f\n", reads.QueriesPerSecond())
+		if len(reads.Samples) > 0 {
+			fmt.Println("-> p50:             ", bench.LatencyPercentile(reads.Samples, 50))
+			fmt.Println("-> p99:             ", bench.LatencyPercentile(reads.Samples, 99))
+			fmt.Println("-> max:             ", reads.Samples[len(reads.Samples)-1])
+		}
+	}
+
+	if result.SchedLatency != nil {
+		fmt.Println()
+		fmt.Println("Scheduling latency:")
+		fmt.Println("-> samples:         ", result.SchedLatency.Samples)
+		fmt.Println("-> max delay:       ", result.SchedLatency.Max)
+		fmt.Println("-> avg delay:       ", result.SchedLatency.Avg)
+	}
+
+	if goroutines := result.Goroutines; goroutines != nil {
+		fmt.Println()
+		fmt.Println("Goroutines:")
+		fmt.Println("-> samples:         ", goroutines.Samples)
+		fmt.Println("-> before the load: ", goroutines.Start)
+		fmt.Println("-> peak:            ", goroutines.Peak)
+		fmt.Printf("-> avg:              %.1f\n", goroutines.Avg)
+		if goroutines.Samples > 0 {
+			// the goroutines started by the writers and their clients besides the threads of the load
+			fmt.Println("-> peak beyond the threads:", goroutines.Peak-goroutines.Start-f.threadsCount)
+		}
+	}
+
+	if result.Allocs != nil {
+		fmt.Println()
+		fmt.Println("Allocations:")
+		fmt.Println("-> mallocs:         ", result.Allocs.Mallocs)
+		fmt.Println("-> bytes allocated: ", result.Allocs.TotalAlloc)
+		fmt.Printf("-> per write:        %.1f allocs, %.0f bytes\n", result.Allocs.MallocsPerWrite(), result.Allocs.BytesPerWrite())
+	}
+
+	if overhead := result.SyncOverhead; overhead != nil {
+		fmt.Println()
+		fmt.Println("Synchronization overhead (all threads):")
+		fmt.Println("-> stop selects:    ", overhead.Selects)
+		fmt.Println("-> pacing:          ", overhead.Pacing)
+		fmt.Println("-> lock waits:      ", overhead.Locks)
+		fmt.Println("-> write calls:     ", overhead.Writes)
+		if overhead.Writes > 0 {
+			fmt.Printf("-> lock waits of write calls: %.2f%%\n", float64(overhead.Locks)/float64(overhead.Writes)*100)
+		}
+	}
+
+	if f.detectBottleneck {
+		printBottleneck(result, cpu, cpuAvailable, f.threadsCount, f.pointsPerCall)
+	}
+
+	if f.fieldKeyChurn > 0 {
+		// threads write iterations from lineProtocolsCount up to the end of the last second
+		first, last := f.lineProtocolsCount, (f.secondsCount+1)*f.lineProtocolsCount-1
+		fmt.Println()
+		fmt.Println("Field key churn:")
+		fmt.Println("-> field keys created (approx.):", last/f.fieldKeyChurn-first/f.fieldKeyChurn+1)
+	}
+}
+
+// printBottleneck prints whether the run is bound by the client or by the server
+func printBottleneck(result bench.Result, cpu time.Duration, cpuAvailable bool, threadsCount int, pointsPerCall int) {
+	fmt.Println()
+	fmt.Println("Bottleneck:")
+	if !cpuAvailable {
+		fmt.Println("-> CPU time of the process is not available on this platform")
+		return
+	}
+	var blocked time.Duration
+	for _, sample := range result.Samples {
+		blocked += sample
+	}
+	elapsed := result.Elapsed
+	points := int64(len(result.Samples) * pointsPerCall)
+	cpuUtilization := float64(cpu) / (float64(elapsed) * float64(runtime.NumCPU()))
+	blockedRatio := float64(blocked) / (float64(elapsed) * float64(threadsCount))
+	fmt.Printf("-> throughput [points/sec]: %v\n", green(int64(float64(points)/elapsed.Seconds())))
+	fmt.Printf("-> CPU utilization: %.1f%% of %v CPUs, CPU time per point: %v\n", cpuUtilization*100, runtime.NumCPU(), cpuPerPoint(cpu, points))
+	fmt.Printf("-> time blocked in Write calls: %.1f%% of the thread time\n", blockedRatio*100)
+	fmt.Printf("-> verdict: %v\n", bottleneckVerdict(cpuUtilization, blockedRatio))
+}
+
+// printCounts prints the counted points of the run, the points with the default tags are counted by the writer
+func printCounts(f flags, config bench.WriterConfig, writer bench.Writer, result bench.Result, expected int) {
+	fmt.Println()
+	fmt.Println("Results:")
+	fmt.Println("-> expected:        ", result.Expected)
+	fmt.Println("-> total:           ", result.Total)
+	if f.appendMode {
+		fmt.Println("-> baseline:        ", result.Baseline)
+	}
+	fmt.Println("-> rate [%]:        ", result.Rate)
+	fmt.Println("-> attempted:       ", result.Attempted)
+	fmt.Println("-> rate of attempted [%]:", result.AttemptedRate)
+	if result.Attempted < int64(expected) {
+		fmt.Println("->", red("the load stopped early"), "by", int64(expected)-result.Attempted, "points, the rate of attempted points tells the loss")
+	}
+	if f.duplicateRate > 0 {
+		first, last := f.lineProtocolsCount, (f.secondsCount+1)*f.lineProtocolsCount-1
+		unique := config.Points.UniqueKeys(f.threadsCount, first, last)
+		fmt.Println("-> expected after dedup:", unique)
+		fmt.Println("-> rate after dedup [%]:", float64(result.Total)/float64(unique)*100)
+	}
+	fmt.Println("-> rate [msg/sec]:  ", green(result.Total/f.secondsCount))
+	if len(result.CountShardTimes) > 0 {
+		fmt.Println("-> count query time:", result.CountTime, "by shards", result.CountShardTimes)
+	} else {
+		fmt.Println("-> count query time:", result.CountTime)
+	}
+	if f.countStabilize {
+		fmt.Println("-> count queries:   ", result.CountPolls)
+	}
+	if f.countTimeShards > 1 {
+		fmt.Println("-> count shards:    ", f.countTimeShards)
+	}
+	if tags := config.DefaultTags; len(tags) > 0 && f.clientType() == "CLIENT_GO_V2" && f.v2WriteMode == "point" {
+		tagged, err := bench.CountTagged(writer, f.measurementName, tags)
+		if err == nil && f.measurementSwitchEvery > 0 {
+			var alternate int
+			alternate, err = bench.CountTagged(writer, bench.AlternateMeasurement(f.measurementName), tags)
+			tagged += alternate
+		}
+		if err != nil {
+			fmt.Println("-> tagged points:    count failed:", err)
+		} else {
+			fmt.Println("-> tagged points:   ", tagged)
+			if tagged < result.Total {
+				fmt.Println("->", red("the default tags are missing"), "on", result.Total-tagged, "points")
+			}
+		}
+	}
+	fmt.Println()
+	fmt.Println("Total time:", result.Elapsed+result.CountTime)
+}
+
+// printClose prints the time of closing the writer and the stats of the writes collected until the writer was closed,
+// deadBatches and deadPoints are the failed batches and their points written into the dead letter file
+func printClose(f flags, config bench.WriterConfig, result bench.Result, closeTime time.Duration, deadBatches int, deadPoints int) {
+	clientType := f.clientType()
+	fmt.Println()
+	fmt.Println("Close:")
+	fmt.Println("-> close/flush time:", closeTime)
+	if sustained := result.Elapsed + closeTime; sustained > 0 {
+		fmt.Printf("-> throughput including close [points/sec]: %v\n", int64(float64(result.Attempted)/sustained.Seconds()))
+	}
+
+	if result.Stats != nil && strings.HasPrefix(clientType, "HTTP_") {
+		stats := result.Stats
+		fmt.Println()
+		fmt.Println("Write requests:")
+		fmt.Println("-> raw client:      ", f.rawClient)
+		if f.hostHeader != "" {
+			fmt.Println("-> host header:     ", f.hostHeader)
+		}
+		fmt.Println("-> failed batches:  ", stats.FailedBatches)
+		if stats.RateLimited > 0 {
+			fmt.Println("-> rate limited:    ", stats.RateLimited)
+		}
+		if stats.FailedBatches > 0 {
+			fmt.Println("   -> network:      ", stats.NetworkErrors)
+			fmt.Println("   -> server (5xx): ", stats.ServerErrors)
+			fmt.Println("   -> client (4xx): ", stats.ClientErrors)
+			fmt.Println("   -> serialization:", stats.SerializationErrors)
+		}
+		fmt.Println("-> partial writes:  ", stats.PartialWrites)
+		fmt.Println("-> dropped points:  ", stats.DroppedPoints)
+		fmt.Println("-> connections:     ", stats.Connections)
+		fmt.Println("-> sent bytes:      ", stats.SentBytes)
+		fmt.Println("-> written bytes:   ", stats.WrittenBytes)
+		fmt.Printf("-> write amplification: %.3f\n", stats.WriteAmplification())
+		if stats.ServerTimed > 0 {
+			serverTime, roundTrip := stats.AverageServerTime(), stats.AverageServerTimedRoundTrip()
+			fmt.Printf("-> server timed:     %v of %v requests\n", stats.ServerTimed, stats.Batches)
+			fmt.Println("   -> avg server:   ", serverTime)
+			fmt.Println("   -> avg round trip:", roundTrip)
+			fmt.Println("   -> avg client+network:", roundTrip-serverTime)
+		}
+	}
+
+	if result.Connections != nil && !strings.HasPrefix(clientType, "HTTP_") {
+		// the HTTP_ writers print their connections with the write requests
+		fmt.Println()
+		fmt.Println("Connections:")
+		fmt.Println("-> dialed:          ", *result.Connections)
+	}
+
+	if result.Stats != nil && clientType == "UDP_V1" {
+		stats := result.Stats
+		fmt.Println()
+		fmt.Println("UDP datagrams:")
+		fmt.Println("-> datagrams:       ", stats.Datagrams)
+		if stats.Datagrams > 0 {
+			fmt.Println("-> avg bytes:       ", stats.SentBytes/stats.Datagrams)
+		}
+		fmt.Println("-> failed sends:    ", stats.NetworkErrors)
+		fmt.Println("-> oversize points: ", stats.OversizePoints)
+		if stats.OversizePoints > 0 {
+			fmt.Printf("-> %s points longer than udpPayloadBytes %v are dropped\n", red("Warning:"), f.udpPayloadBytes)
+		}
+	}
+
+	if f.floatFormat != "" && result.Stats != nil {
+		stats := result.Stats
+		fmt.Println()
+		fmt.Println("Payload:")
+		fmt.Println("-> float format:    ", f.floatFormat)
+		if written := stats.Batches - stats.FailedBatches; written > 0 {
+			fmt.Println("-> avg batch bytes: ", stats.WrittenBytes/written)
+		}
+		if stats.FailedBatches == 0 && result.Attempted > 0 {
+			fmt.Printf("-> avg point bytes:  %.1f\n", float64(stats.WrittenBytes)/float64(result.Attempted))
+		}
+	}
+
+	if result.Pending != nil {
+		fmt.Println()
+		fmt.Printf("Pending points (max %v, %s):\n", result.Pending.MaxPending, f.pendingPolicy)
+		fmt.Println("-> blocked points:  ", result.Pending.Blocked)
+		fmt.Println("-> blocked time:    ", result.Pending.BlockedTime)
+		fmt.Println("-> dropped points:  ", result.Pending.Dropped)
+	}
+
+	if failures := result.Errors; failures != nil && (failures.Requeued > 0 || failures.Dropped > 0 || failures.Aborted) {
+		fmt.Println()
+		fmt.Printf("Failed writes (%s):\n", failures.Policy)
+		fmt.Println("-> requeued points: ", failures.Requeued)
+		fmt.Println("-> dropped points:  ", failures.Dropped)
+		if failures.Aborted {
+			fmt.Println("-> aborted by:      ", failures.AbortError)
+		}
+	}
+
+	if config.DeadLetters != nil {
+		fmt.Println()
+		fmt.Println("Dead letters:")
+		fmt.Println("-> failed batches:  ", deadBatches)
+		fmt.Println("-> points:          ", deadPoints)
+		fmt.Println("-> file:            ", f.deadLetterFile)
+	}
+
+	if config.Retries != nil && strings.HasPrefix(clientType, "HTTP_") {
+		retries := config.Retries.Stats()
+		fmt.Println()
+		fmt.Println("Retries:")
+		fmt.Printf("-> used:             %v/%v\n", retries.Used, retries.Budget)
+		fmt.Println("-> abandoned writes:", retries.Abandoned)
+	}
+}
+
+// selfReportPoint returns the summary point of the run written by the self report
+func selfReportPoint(f flags, config bench.WriterConfig, result bench.Result) *write.Point {
+	clientType := f.clientType()
+	point := influxdb2.NewPointWithMeasurement(f.selfReportMeasurement).
+		AddTag("type", clientType).
+		AddTag("measurement", f.measurementName).
+		AddField("threadsCount", f.threadsCount).
+		AddField("secondsCount", f.secondsCount).
+		AddField("lineProtocolsCount", f.lineProtocolsCount).
+		AddField("pointsPerCall", f.pointsPerCall).
+		AddField("batchSize", f.batchSize).
+		AddField("expected", result.Expected).
+		SetTime(time.Now())
+	if config.Points.RunTag != "" {
+		point.AddTag("run", config.Points.RunTag)
+	}
+	if f.label != "" {
+		point.AddTag("label", f.label)
+	}
+	if strings.HasPrefix(clientType, "HTTP_") {
+		point.AddTag("rawClient", f.rawClient)
+	}
+	if result.Counted {
+		point.AddField("total", result.Total).
+			AddField("rate_percent", result.Rate).
+			AddField("rate_msg_sec", result.Total/f.secondsCount)
+	}
+	for _, percentile := range result.Percentiles {
+		point.AddField(fmt.Sprintf("latency_p%v_ns", percentile.Percentile), percentile.Latency.Nanoseconds())
+	}
+	if len(result.Samples) > 0 {
+		point.AddField("latency_max_ns", result.Samples[len(result.Samples)-1].Nanoseconds())
+	}
+	if result.Stats != nil && strings.HasPrefix(clientType, "HTTP_") {
+		stats := result.Stats
+		point.AddField("failed_batches", stats.FailedBatches)
+		if stats.WrittenBytes > 0 {
+			point.AddField("write_amplification", stats.WriteAmplification())
+		}
+		if stats.Batches > 0 {
+			point.AddField("error_rate", float64(stats.FailedBatches)/float64(stats.Batches))
+		}
+	}
+	return point
+}
+
+// reportLeaks reports goroutines that were started after the before snapshot and still run after the writers were closed
+func reportLeaks(before int) {
+	// goroutines of closed writers may need a moment to finish
+	after := runtime.NumGoroutine()
+	for deadline := time.Now().Add(time.Second); after > before && time.Now().Before(deadline); after = runtime.NumGoroutine() {
+		time.Sleep(10 * time.Millisecond)
+	}
+	fmt.Println()
+	fmt.Println("Goroutines:")
+	fmt.Println("-> before run:      ", before)
+	fmt.Println("-> after close:     ", after)
+	if after > before {
+		fmt.Println("-> leaked:          ", after-before)
+		fmt.Println()
+		_ = pprof.Lookup("goroutine").WriteTo(os.Stdout, 1)
+	}
+}
+
+// printRanking prints writers of the ALL type ranked by throughput relative to the fastest one
+func printRanking(results []bench.RankResult, pointsPerCall int) {
+	fmt.Println("Ranking by throughput:")
+	fastest := results[0].Throughput(pointsPerCall)
+	for i, result := range results {
+		throughput := result.Throughput(pointsPerCall)
+		relative := 0.0
+		if fastest > 0 {
+			relative = throughput / fastest * 100
+		}
+		fmt.Printf("%v. %-13s rate [points/sec]: %10.1f %6.1f%%", i+1, result.WriterType, throughput, relative)
+		if len(result.Result.Percentiles) > 0 {
+			latency := result.Result.Percentiles[0]
+			fmt.Printf(", p%v latency: %v", latency.Percentile, latency.Latency)
+		}
+		if result.Result.Counted {
+			fmt.Printf(", total: %v (%.1f%%)", result.Result.Total, result.Result.Rate)
+		}
+		fmt.Println()
+	}
+}
+
+// printCompareV2 prints the throughput and latency delta of the async and sync writers of the COMPARE_V2 type
+func printCompareV2(results []bench.CompareResult, pointsPerCall int, percentiles []float64, skipCount bool) {
+	asyncResult, syncResult := results[0], results[1]
+	fmt.Println("Comparison (async vs sync):")
+	for _, result := range results {
+		fmt.Printf("-> %-5s rate [points/sec]: %.1f, failed writes: %v", result.Name, result.Throughput(pointsPerCall), result.Failed)
+		if !skipCount {
+			fmt.Printf(", total: %v", result.Total)
+		}
+		fmt.Println()
+	}
+	if syncResult.Throughput(pointsPerCall) > 0 {
+		fmt.Printf("-> throughput delta:  %+.1f%%\n", (asyncResult.Throughput(pointsPerCall)/syncResult.Throughput(pointsPerCall)-1)*100)
+	}
+	if len(asyncResult.Samples) > 0 && len(syncResult.Samples) > 0 {
+		for _, percentile := range percentiles {
+			asyncLatency, syncLatency := bench.LatencyPercentile(asyncResult.Samples, percentile), bench.LatencyPercentile(syncResult.Samples, percentile)
+			fmt.Printf("-> %-18s async %v, sync %v, delta %v\n", fmt.Sprintf("p%v latency:", percentile), asyncLatency, syncLatency, asyncLatency-syncLatency)
+		}
+	}
+}
+
+// printRepeated prints the results of repetitions and their mean and standard deviation
+func printRepeated(results []bench.Result, summary bench.RepeatSummary, pointsPerCall int, percentiles []float64) {
+	fmt.Println("Repetitions:")
+	for i, result := range results {
+		fmt.Printf("%v. rate [points/sec]: %10.1f", i+1, bench.ResultThroughput(result, pointsPerCall))
+		for _, percentile := range result.Percentiles {
+			fmt.Printf(", p%v latency: %v", percentile.Percentile, percentile.Latency)
+		}
+		if result.Counted {
+			fmt.Printf(", total: %v (%.1f%%)", result.Total, result.Rate)
+		}
+		fmt.Println()
+	}
+	fmt.Println()
+	fmt.Printf("Summary of %v repetitions (mean ± stddev):\n", summary.Repetitions)
+	fmt.Printf("-> rate [points/sec]: %.1f ± %.1f\n", summary.Throughput.Mean, summary.Throughput.Stddev)
+	for i, percentile := range percentiles {
+		latency := summary.Percentiles[i]
+		fmt.Printf("%-21s %v ± %v\n", fmt.Sprintf("-> p%v latency:", percentile), time.Duration(latency.Mean), time.Duration(latency.Stddev))
+	}
+	if summary.Loss != nil {
+		fmt.Printf("%-21s %.2f ± %.2f\n", "-> lost points [%]:", summary.Loss.Mean, summary.Loss.Stddev)
+	}
+	if summary.FailedRequests != nil {
+		fmt.Printf("%-21s %.2f ± %.2f\n", "-> failed requests [%]:", summary.FailedRequests.Mean, summary.FailedRequests.Stddev)
+	}
+}
+
+// cpuPerPoint returns CPU time spent by the process per written point
+func cpuPerPoint(cpu time.Duration, points int64) time.Duration {
+	if points == 0 {
+		return 0
+	}
+	return cpu / time.Duration(points)
+}
+
+// bottleneckVerdict estimates what limits the throughput of the run. The process is client-bound when
+// it saturates the CPUs, otherwise the threads wait for the server (or for the asynchronous write buffer
+// of the v2 client that waits for the server) when they spend most of their time blocked in Write calls.
+func bottleneckVerdict(cpuUtilization float64, blockedRatio float64) string {
+	switch {
+	case cpuUtilization >= 0.8:
+		return "client-bound (the CPUs are saturated by the benchmark process)"
+	case blockedRatio >= 0.5:
+		return "server/network-bound (the threads are blocked waiting for writes to complete)"
+	default:
+		return "neither saturated (increase threadsCount or lineProtocolsCount to put more load)"
+	}
+}
+
+// printConnectionSetups prints the measured cold start of writers separately from the steady-state latency
+func printConnectionSetups(setups []bench.ConnectionSetup) {
+	printed := false
+	for _, setup := range setups {
+		if !setup.Measured {
+			continue
+		}
+		if !printed {
+			fmt.Println()
+			fmt.Println("Connection setup (first write):")
+			printed = true
+		}
+		prefix := "->"
+		if setup.Url != "" {
+			prefix = "-> " + setup.Url + ":"
+		}
+		firstWrite := "failed"
+		if setup.FirstWrite > 0 {
+			firstWrite = setup.FirstWrite.String()
+		}
+		fmt.Printf("%s connect: %v, got connection: %v (reused: %v), first write: %v\n", prefix, setup.Connect, setup.GotConn, setup.Reused, firstWrite)
+	}
+}
+
+// sparkBars are the bars of the sparkline from the lowest to the highest value
+var sparkBars = []rune("▁▂▃▄▅▆▇█")
+
+// printTimeline prints the points written within buckets of the run and a sparkline of them
+func printTimeline(buckets []bench.TimelineBucket, length time.Duration) {
+	var highest int64
+	for _, bucket := range buckets {
+		if bucket.Points > highest {
+			highest = bucket.Points
+		}
+	}
+	fmt.Println()
+	fmt.Printf("Throughput timeline (%v buckets):\n", length)
+	sparkline := make([]rune, len(buckets))
+	for i, bucket := range buckets {
+		bar := 0
+		if highest > 0 {
+			bar = int(bucket.Points * int64(len(sparkBars)-1) / highest)
+		}
+		sparkline[i] = sparkBars[bar]
+		fmt.Printf("-> %8v: %10v points, %6v writes, rate [points/sec]: %.1f\n", bucket.Start, bucket.Points, bucket.Writes, bucket.PointsPerSecond(length))
+	}
+	fmt.Println("->", string(sparkline))
+}
+
+// dumpLatencies writes the latencies into the file at path
+func dumpLatencies(path string, latencies [][]time.Duration) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := bench.WriteLatencies(file, latencies); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// printOrderReport prints the outcome of the verification of ordered timestamps
+func printOrderReport(report bench.OrderReport, err error) {
+	fmt.Println()
+	fmt.Println("Order verification:")
+	if err != nil {
+		fmt.Println("-> failed:", err)
+		return
+	}
+	fmt.Printf("-> sampled series:   %v with %v points\n", report.Series, report.Points)
+	fmt.Printf("-> timestamps:       %v to %v\n", report.First, report.Last)
+	fmt.Println("-> counted points:  ", report.Counted)
+	fmt.Println("-> missing points:  ", report.Missing)
+	for _, anomaly := range report.Anomalies {
+		fmt.Println("-> anomaly:         ", anomaly)
+	}
+	if report.Ok() {
+		fmt.Println("-> verdict:          ordered without gaps")
+	} else {
+		fmt.Println("-> verdict:          ANOMALIES FOUND")
+	}
+}
+
+// printLatencies prints the latency percentiles and the max latency of the result
+func printLatencies(result bench.Result) {
+	if len(result.Samples) == 0 {
+		fmt.Println("-> no writes recorded")
+		return
+	}
+	for _, percentile := range result.Percentiles {
+		fmt.Printf("%-20s %v\n", fmt.Sprintf("-> p%v:", percentile.Percentile), percentile.Latency)
+	}
+	fmt.Printf("%-20s %v\n", "-> max:", result.Samples[len(result.Samples)-1])
+}
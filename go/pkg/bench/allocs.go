@@ -0,0 +1,34 @@
+package bench
+
+import "runtime"
+
+// Allocs are heap allocations of the whole process during the load, including the background work of the writer
+type Allocs struct {
+	Mallocs    uint64
+	TotalAlloc uint64
+	// Writes is the count of measured Write calls the allocations are divided by
+	Writes int
+}
+
+// MallocsPerWrite returns the average count of heap allocations per Write call
+func (a Allocs) MallocsPerWrite() float64 {
+	if a.Writes == 0 {
+		return 0
+	}
+	return float64(a.Mallocs) / float64(a.Writes)
+}
+
+// BytesPerWrite returns the average bytes allocated per Write call
+func (a Allocs) BytesPerWrite() float64 {
+	if a.Writes == 0 {
+		return 0
+	}
+	return float64(a.TotalAlloc) / float64(a.Writes)
+}
+
+// allocsSince returns the allocations since the memory statistics before
+func allocsSince(before runtime.MemStats) Allocs {
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
+	return Allocs{Mallocs: after.Mallocs - before.Mallocs, TotalAlloc: after.TotalAlloc - before.TotalAlloc}
+}
@@ -0,0 +1,162 @@
+package bench
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// AutotuneConfig configures the AUTOTUNE search of the highest sustainable throughput
+type AutotuneConfig struct {
+	MaxWorkers         int
+	SecondsCount       int
+	LineProtocolsCount int
+	PointsPerCall      int
+	MeasurementName    string
+	// Step is how much workers are added after a stable second
+	Step            int
+	TargetP99       time.Duration
+	TargetErrorRate float64
+	// Quiet suppresses the progress output of every second
+	Quiet bool
+}
+
+// AutotuneResult is the best throughput of a stable second and its concurrency, BestWorkers is zero
+// when no second was stable
+type AutotuneResult struct {
+	BestWorkers    int
+	BestThroughput int
+}
+
+// autotuneWindow collects writes of all workers during one second of tuning
+type autotuneWindow struct {
+	lock      sync.Mutex
+	points    int
+	latencies []time.Duration
+}
+
+func (w *autotuneWindow) record(points int, latency time.Duration) {
+	w.lock.Lock()
+	w.points += points
+	w.latencies = append(w.latencies, latency)
+	w.lock.Unlock()
+}
+
+// reset returns the collected writes and starts a new window
+func (w *autotuneWindow) reset() (int, []time.Duration) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	points, latencies := w.points, w.latencies
+	w.points, w.latencies = 0, nil
+	return points, latencies
+}
+
+// RunAutotune adjusts the count of active workers every second (AIMD). The count is increased by a step
+// while p99 latency and error rate stay within the targets, otherwise it is halved.
+// The best throughput of a stable second and its concurrency are returned at the end.
+// The error rate is taken from WriteStats, so the writer has to be a StatsReporter.
+func RunAutotune(writer Writer, config AutotuneConfig) (AutotuneResult, error) {
+	reporter, ok := writer.(StatsReporter)
+	if !ok {
+		return AutotuneResult{}, fmt.Errorf("the writer doesn't report failed writes, the error rate can't be tuned")
+	}
+	window := &autotuneWindow{}
+	stops := make([]chan bool, config.MaxWorkers)
+	dones := make([]chan bool, config.MaxWorkers)
+	nextIterations := make([]int, config.MaxWorkers)
+	active := 0
+	resize := func(count int) {
+		for active < count {
+			if dones[active] != nil {
+				// the previous worker with the same id has to finish before its iterations continue
+				<-dones[active]
+			}
+			stops[active] = make(chan bool)
+			dones[active] = make(chan bool)
+			go autotuneWorker(active+1, writer, config, &nextIterations[active], window, stops[active], dones[active])
+			active++
+		}
+		for active > count {
+			active--
+			close(stops[active])
+		}
+	}
+
+	lastStats := reporter.WriteStats()
+	bestWorkers, bestThroughput := 0, 0
+	workers := 1
+	resize(workers)
+	for second := 1; second <= config.SecondsCount; second++ {
+		time.Sleep(time.Second)
+		points, latencies := window.reset()
+		var p99 time.Duration
+		if len(latencies) > 0 {
+			sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+			p99 = LatencyPercentile(latencies, 99)
+		}
+		errorRate := 0.0
+		stats := reporter.WriteStats()
+		if batches := stats.Batches - lastStats.Batches; batches > 0 {
+			errorRate = float64(stats.FailedBatches-lastStats.FailedBatches) / float64(batches)
+		}
+		lastStats = stats
+		stable := p99 <= config.TargetP99 && errorRate <= config.TargetErrorRate
+		if !config.Quiet {
+			fmt.Printf("second %v: workers: %v, rate [msg/sec]: %v, p99: %v, error rate: %.4f, stable: %v\n",
+				second, active, points, p99, errorRate, stable)
+		}
+		if stable {
+			if points > bestThroughput {
+				bestWorkers, bestThroughput = active, points
+			}
+			workers += config.Step
+			if workers > config.MaxWorkers {
+				workers = config.MaxWorkers
+			}
+		} else {
+			workers /= 2
+			if workers < 1 {
+				workers = 1
+			}
+		}
+		resize(workers)
+	}
+	resize(0)
+	for _, done := range dones {
+		if done != nil {
+			<-done
+		}
+	}
+
+	return AutotuneResult{BestWorkers: bestWorkers, BestThroughput: bestThroughput}, nil
+}
+
+// autotuneWorker writes lineProtocolsCount points every second until stopped
+func autotuneWorker(id int, writer Writer, config AutotuneConfig, nextIteration *int, window *autotuneWindow, stop <-chan bool, done chan<- bool) {
+	defer close(done)
+	iterations := make([]int, 0, config.PointsPerCall)
+	for {
+		burstStart := time.Now()
+		for j := 0; j < config.LineProtocolsCount; j += config.PointsPerCall {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			iterations = iterations[:0]
+			for k := j; k < config.LineProtocolsCount && k < j+config.PointsPerCall; k++ {
+				iterations = append(iterations, *nextIteration)
+				*nextIteration++
+			}
+			writeStart := time.Now()
+			writer.Write(id, config.MeasurementName, iterations)
+			window.record(len(iterations), time.Since(writeStart))
+		}
+		select {
+		case <-stop:
+			return
+		case <-time.After(time.Until(burstStart.Add(time.Second))):
+		}
+	}
+}
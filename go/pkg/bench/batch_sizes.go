@@ -0,0 +1,96 @@
+package bench
+
+import (
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// BatchSizeStats is the distribution of the sizes in points of the batches sent by the raw writers with
+// WriterConfig.BatchSizeMin and BatchSizeMax
+type BatchSizeStats struct {
+	Batches int
+	Min     int
+	Max     int
+	Avg     float64
+	P50     int
+	P90     int
+	P99     int
+}
+
+// batchSizeReporter is implemented by writers that record the sizes of their batches
+type batchSizeReporter interface {
+	// batchSizes returns the sizes of all sent batches, it is nil unless the sizes are drawn from a range
+	batchSizes() []int
+}
+
+// mergeBatchSizes returns the sizes of batches of all writers that record them
+func mergeBatchSizes(writers []Writer) []int {
+	var sizes []int
+	for _, writer := range writers {
+		if reporter, ok := writer.(batchSizeReporter); ok {
+			sizes = append(sizes, reporter.batchSizes()...)
+		}
+	}
+	return sizes
+}
+
+// newBatchSizeStats returns the distribution of the sizes
+func newBatchSizeStats(sizes []int) BatchSizeStats {
+	sorted := append([]int(nil), sizes...)
+	sort.Ints(sorted)
+	stats := BatchSizeStats{Batches: len(sorted)}
+	if len(sorted) == 0 {
+		return stats
+	}
+	total := 0
+	for _, size := range sorted {
+		total += size
+	}
+	percentile := func(p float64) int {
+		return sorted[int(float64(len(sorted)-1)*p/100)]
+	}
+	stats.Min, stats.Max = sorted[0], sorted[len(sorted)-1]
+	stats.Avg = float64(total) / float64(len(sorted))
+	stats.P50, stats.P90, stats.P99 = percentile(50), percentile(90), percentile(99)
+	return stats
+}
+
+// batchSizes draws the size of the next batch of a raw writer uniformly from min to max inclusive and records
+// the sizes of the cut batches, it is guarded by the lock of the writer
+type batchSizes struct {
+	min    int
+	max    int
+	random *rand.Rand
+	sizes  []int
+}
+
+// newBatchSizes returns nil unless WriterConfig.BatchSizeMax is set, a zero BatchSizeMin means BatchSize
+func newBatchSizes(config WriterConfig) *batchSizes {
+	if config.BatchSizeMax == 0 {
+		return nil
+	}
+	min := config.BatchSizeMin
+	if min == 0 {
+		min = config.BatchSize
+	}
+	return &batchSizes{min: int(min), max: int(config.BatchSizeMax), random: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+// next records the size of the cut batch and returns the size of the next batch, it is fixed when b is nil
+func (b *batchSizes) next(cut int, fixed int) int {
+	if b == nil {
+		return fixed
+	}
+	if cut > 0 {
+		b.sizes = append(b.sizes, cut)
+	}
+	return b.min + b.random.Intn(b.max-b.min+1)
+}
+
+func (b *batchSizes) recorded() []int {
+	if b == nil {
+		return nil
+	}
+	return append([]int(nil), b.sizes...)
+}
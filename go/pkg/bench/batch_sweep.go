@@ -0,0 +1,98 @@
+package bench
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// BatchSweepConfig configures the BATCH_SWEEP search of the batch size
+type BatchSweepConfig struct {
+	// MinBatchSize is the first batch size, it is multiplied by Factor up to MaxBatchSize
+	MinBatchSize uint
+	MaxBatchSize uint
+	Factor       uint
+	// Plateau is the lowest relative gain of throughput over the best smaller batch size that continues the sweep
+	Plateau float64
+	// TargetErrorRate is the highest ratio of failed batches, or of points not counted by writers without WriteStats
+	TargetErrorRate float64
+}
+
+// BatchSweepStep is the outcome of the run of one batch size
+type BatchSweepStep struct {
+	BatchSize uint
+	// Throughput is the rate of written points per second
+	Throughput float64
+	ErrorRate  float64
+}
+
+// BatchSweepResult are the runs of all batch sizes and the recommended one, Recommended is zero when
+// already the first batch size failed the TargetErrorRate
+type BatchSweepResult struct {
+	Steps       []BatchSweepStep
+	Recommended uint
+	// Stopped tells why the sweep stopped
+	Stopped string
+}
+
+// errorRate returns the ratio of failed batches of writers reporting WriteStats, or the ratio of points
+// that were not counted, it is zero without both
+func errorRate(result Result) float64 {
+	if result.Stats != nil {
+		if result.Stats.Batches == 0 {
+			return 0
+		}
+		return float64(result.Stats.FailedBatches) / float64(result.Stats.Batches)
+	}
+	if result.Counted && result.Expected > 0 && result.Total < result.Expected {
+		return float64(result.Expected-result.Total) / float64(result.Expected)
+	}
+	return 0
+}
+
+// RunBatchSweep runs the load by writers created by newWriter with batch sizes growing geometrically,
+// each into its own measurement derived from config.MeasurementName. The sweep stops when the error rate
+// exceeds the target or the throughput stops growing, the best batch size before is recommended.
+// The writers are closed after their run, the Writer of config is not used and SkipCount is ignored by writers
+// without WriteStats.
+func RunBatchSweep(newWriter func(WriterConfig) Writer, writerConfig WriterConfig, config Config, sweep BatchSweepConfig) (BatchSweepResult, error) {
+	var result BatchSweepResult
+	best := 0.0
+	for batchSize := sweep.MinBatchSize; batchSize <= sweep.MaxBatchSize; batchSize *= sweep.Factor {
+		if !config.Quiet {
+			fmt.Println("Writing by batches of", batchSize, "points ...")
+		}
+		stepConfig := writerConfig
+		stepConfig.BatchSize = batchSize
+		writer := newWriter(stepConfig)
+		load := config
+		load.Writer = writer
+		load.MeasurementName = config.MeasurementName + "_batch" + strconv.FormatUint(uint64(batchSize), 10)
+		// points of writers without WriteStats are counted to find out the errors
+		_, reportsStats := writer.(StatsReporter)
+		load.SkipCount = config.SkipCount && reportsStats
+		run, err := Run(load)
+		if err != nil {
+			writer.Close()
+			return result, fmt.Errorf("batch size %v failed: %v", batchSize, err)
+		}
+		if err := writer.Close(); err != nil {
+			return result, fmt.Errorf("closing of the writer of batch size %v failed: %v", batchSize, err)
+		}
+		if !config.Quiet {
+			fmt.Println()
+		}
+		step := BatchSweepStep{BatchSize: batchSize, Throughput: ResultThroughput(run, config.PointsPerCall), ErrorRate: errorRate(run)}
+		result.Steps = append(result.Steps, step)
+		if step.ErrorRate > sweep.TargetErrorRate {
+			result.Stopped = fmt.Sprintf("error rate %.4f exceeded the target at batch size %v", step.ErrorRate, batchSize)
+			return result, nil
+		}
+		if result.Recommended > 0 && step.Throughput < best*(1+sweep.Plateau) {
+			result.Stopped = fmt.Sprintf("throughput plateaued at batch size %v", batchSize)
+			return result, nil
+		}
+		best, result.Recommended = step.Throughput, batchSize
+	}
+	result.Stopped = fmt.Sprintf("the maximum batch size %v was reached", sweep.MaxBatchSize)
+	return result, nil
+}
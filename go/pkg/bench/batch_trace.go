@@ -0,0 +1,73 @@
+package bench
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// BatchRecord is the timing of one batch sent by a writer, or of one flush of the asynchronous V2 write API
+type BatchRecord struct {
+	// Kind is "batch" for a sent batch, or "flush" for a flush of the asynchronous write API whose batches are not observable
+	Kind string `json:"kind"`
+	// StartNs is the start of the batch in nanoseconds since the start of the trace
+	StartNs    int64 `json:"startNs"`
+	DurationNs int64 `json:"durationNs"`
+	// Points and Bytes are zero for flushes
+	Points int `json:"points"`
+	Bytes  int `json:"bytes,omitempty"`
+	// Attempts counts requests of the batch including retries
+	Attempts int    `json:"attempts,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// BatchTrace writes a BatchRecord of every batch as a JSON line, it is shared by the writers of a run
+type BatchTrace struct {
+	start   time.Time
+	lock    sync.Mutex
+	output  *bufio.Writer
+	encoder *json.Encoder
+	records int
+	err     error
+}
+
+func NewBatchTrace(output io.Writer) *BatchTrace {
+	buffered := bufio.NewWriter(output)
+	return &BatchTrace{start: time.Now(), output: buffered, encoder: json.NewEncoder(buffered)}
+}
+
+// record writes the record of the batch started at start, a nil trace records nothing
+func (t *BatchTrace) record(kind string, start time.Time, points int, bytes int, attempts int, err error) {
+	if t == nil {
+		return
+	}
+	record := BatchRecord{
+		Kind:       kind,
+		StartNs:    int64(start.Sub(t.start)),
+		DurationNs: int64(time.Since(start)),
+		Points:     points,
+		Bytes:      bytes,
+		Attempts:   attempts,
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.err == nil {
+		t.err = t.encoder.Encode(record)
+		t.records++
+	}
+}
+
+// Flush writes the buffered records and returns the count of all written records, or the first error of writing
+func (t *BatchTrace) Flush() (int, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.err == nil {
+		t.err = t.output.Flush()
+	}
+	return t.records, t.err
+}
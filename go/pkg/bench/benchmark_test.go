@@ -0,0 +1,37 @@
+package bench
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func BenchmarkWriteV1(b *testing.B) {
+	benchmarkWrite(b, "CLIENT_GO_V1")
+}
+
+func BenchmarkWriteV2(b *testing.B) {
+	benchmarkWrite(b, "CLIENT_GO_V2")
+}
+
+// benchmarkWrite writes b.N points of one series by b.N Write calls of the writer of writerType into
+// the fake InfluxDB, points left in the buffers of the writer are sent before the timer stops
+func benchmarkWrite(b *testing.B, writerType string) {
+	server := httptest.NewServer(newFakeInflux())
+	defer server.Close()
+	writer := NewWriter(writerType, server.URL, WriterConfig{BatchSize: 1000, Points: PointOptions{TimestampScale: 1}})
+	iterations := make([]int, 1)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		iterations[0] = i
+		writer.Write(1, "benchmark", iterations)
+	}
+	if writer, ok := writer.(flusher); ok {
+		writer.Flush()
+	}
+	b.StopTimer()
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "points/s")
+	if err := writer.Close(); err != nil {
+		b.Fatal(err)
+	}
+}
@@ -0,0 +1,38 @@
+package bench
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// ClockSkewWarning is the skew of the server clock that affects time ranges of count queries, it exceeds
+// the resolution of ClockSkew
+const ClockSkewWarning = 2 * time.Second
+
+// ClockSkew estimates how much the clock of the InfluxDB of writerType is ahead of the local clock, it is negative
+// when the server clock is behind, the server time is taken from the Date header of the health endpoint, so the
+// estimate is precise to about half a second plus half of the round trip
+func ClockSkew(writerType string, serverUrl string) (time.Duration, error) {
+	endpoint := healthEndpoint(writerType, serverUrl)
+	httpClient := &http.Client{Timeout: 5 * time.Second}
+	defer httpClient.CloseIdleConnections()
+	sent := time.Now()
+	response, err := httpClient.Get(endpoint)
+	if err != nil {
+		return 0, err
+	}
+	received := time.Now()
+	defer response.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, response.Body)
+	date, err := http.ParseTime(response.Header.Get("Date"))
+	if err != nil {
+		return 0, fmt.Errorf("%s responded without a valid Date header: %q", endpoint, response.Header.Get("Date"))
+	}
+	// the Date header is truncated to seconds, the server time was in the middle of the second on average
+	server := date.Add(500 * time.Millisecond)
+	client := sent.Add(received.Sub(sent) / 2)
+	return server.Sub(client), nil
+}
@@ -0,0 +1,70 @@
+package bench
+
+import (
+	"fmt"
+	"github.com/influxdata/influxdb-client-go"
+	"sync/atomic"
+	"time"
+)
+
+// CompareResult is the outcome of one writer of the COMPARE_V2 type
+type CompareResult struct {
+	// Name is "async" for WriteApi and "sync" for WriteApiBlocking
+	Name    string
+	Writes  int
+	Elapsed time.Duration
+	// Samples are sorted latencies of all Write calls
+	Samples []time.Duration
+	// Total is the count of points in the measurement of the writer, it is zero when counting is skipped
+	Total  int
+	Failed int64
+}
+
+// Throughput returns the rate of written points per second
+func (r CompareResult) Throughput(pointsPerCall int) float64 {
+	return float64(r.Writes*pointsPerCall) / r.Elapsed.Seconds()
+}
+
+// RunCompareV2 runs the asynchronous WriteApi and the blocking WriteApiBlocking of the V2 client back to back
+// with identical parameters of the load, each into its own measurement derived from config.MeasurementName.
+// The Writer of config is not used.
+func RunCompareV2(serverUrl string, writerConfig WriterConfig, config Config) ([]CompareResult, error) {
+	serverUrl = ServerUrl("CLIENT_GO_V2", serverUrl)
+	constructors := []struct {
+		name      string
+		newWriter func(client influxdb2.Client, config WriterConfig) *WriterV2
+	}{
+		{"async", NewWriterV2},
+		{"sync", NewWriterV2Blocking},
+	}
+	results := make([]CompareResult, len(constructors))
+	for i, constructor := range constructors {
+		if !config.Quiet {
+			fmt.Println("Writing by", constructor.name, "WriteApi ...")
+		}
+		writer := constructor.newWriter(newClientV2(serverUrl, writerConfig.AuthToken, writerConfig.BatchSize, writerConfig.Points.precision(), writerConfig.DefaultTags), writerConfig)
+		load := config
+		load.Writer = writer
+		load.MeasurementName = config.MeasurementName + "_" + constructor.name
+		run, err := Run(load)
+		if err != nil {
+			writer.Close()
+			return nil, err
+		}
+		results[i] = CompareResult{
+			Name:    constructor.name,
+			Writes:  len(run.Samples),
+			Elapsed: run.Elapsed,
+			Samples: run.Samples,
+			Total:   run.Total,
+			Failed:  atomic.LoadInt64(&writer.failedWrites),
+		}
+		if err := writer.Close(); err != nil {
+			return nil, fmt.Errorf("closing of the %s writer failed: %v", constructor.name, err)
+		}
+		if !config.Quiet {
+			fmt.Println()
+		}
+	}
+	return results, nil
+}
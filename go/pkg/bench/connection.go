@@ -0,0 +1,154 @@
+package bench
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// ConnectionSetup is the cold start of a writer measured on its first write request
+type ConnectionSetup struct {
+	// Url is the endpoint of the writer, it is set only for writers of WriterMulti
+	Url string
+	// Measured tells whether the first write request was traced
+	Measured bool
+	// Reused tells whether the first request got an already established connection
+	Reused bool
+	// Connect is the time of establishing the TCP connection and the TLS handshake
+	Connect time.Duration
+	// GotConn is the time from the start of the first request until it got its connection
+	GotConn time.Duration
+	// FirstWrite is the time of the whole first write request, it is zero when the write failed
+	FirstWrite time.Duration
+}
+
+// ConnectionReporter is implemented by writers that measure setup of their first connection
+type ConnectionReporter interface {
+	ConnectionSetup() ConnectionSetup
+}
+
+// ConnectionCounter is implemented by writers that count the TCP connections they dial
+type ConnectionCounter interface {
+	// Connections returns the count of dialed connections, or false when the writer can't count them
+	Connections() (int64, bool)
+}
+
+// countConnections counts the connections dialed by the HTTP client of the client library into connections
+func countConnections(client interface{}, connections *int64, path ...string) bool {
+	return wrapTransport(client, func(transport http.RoundTripper) http.RoundTripper {
+		return &countingTransport{transport: transport, connections: connections}
+	}, path...)
+}
+
+// wrapTransport replaces the transport of the HTTP client of the client library by wrap of it, the HTTP client
+// is found by the path of unexported fields of the library as it doesn't expose it. It returns false when
+// the path doesn't lead to an *http.Client, like after an upgrade of the library.
+func wrapTransport(client interface{}, wrap func(transport http.RoundTripper) http.RoundTripper, path ...string) bool {
+	value := reflect.ValueOf(client)
+	for _, name := range path {
+		for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+			if value.IsNil() {
+				return false
+			}
+			value = value.Elem()
+		}
+		if value.Kind() != reflect.Struct {
+			return false
+		}
+		if value = value.FieldByName(name); !value.IsValid() {
+			return false
+		}
+	}
+	if value.Type() != reflect.TypeOf(&http.Client{}) || value.IsNil() {
+		return false
+	}
+	// the value of an unexported field can't be converted by Interface
+	httpClient := (*http.Client)(unsafe.Pointer(value.Pointer()))
+	transport := httpClient.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	httpClient.Transport = wrap(transport)
+	return true
+}
+
+// countingTransport counts the connections dialed for its requests by httptrace
+type countingTransport struct {
+	transport   http.RoundTripper
+	connections *int64
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	trace := &httptrace.ClientTrace{
+		ConnectDone: func(network, addr string, err error) {
+			if err == nil {
+				atomic.AddInt64(t.connections, 1)
+			}
+		},
+	}
+	// the trace is composed with the trace of the request, like the one of connectionTrace
+	return t.transport.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+}
+
+// connectionTrace traces the first write request of a writer by httptrace
+type connectionTrace struct {
+	once  sync.Once
+	lock  sync.Mutex
+	setup ConnectionSetup
+}
+
+// trace returns ctx with a trace for the first request, later requests get ctx untouched.
+// The returned function has to be called with the outcome of the request.
+func (t *connectionTrace) trace(ctx context.Context) (context.Context, func(err error)) {
+	traced := false
+	t.once.Do(func() { traced = true })
+	if !traced {
+		return ctx, func(error) {}
+	}
+	start := time.Now()
+	var connectStart time.Time
+	trace := &httptrace.ClientTrace{
+		ConnectStart: func(network, addr string) {
+			t.lock.Lock()
+			connectStart = time.Now()
+			t.lock.Unlock()
+		},
+		ConnectDone: func(network, addr string, err error) {
+			t.lock.Lock()
+			t.setup.Connect = time.Since(connectStart)
+			t.lock.Unlock()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.lock.Lock()
+			t.setup.Connect = time.Since(connectStart)
+			t.lock.Unlock()
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.lock.Lock()
+			t.setup.GotConn = time.Since(start)
+			t.setup.Reused = info.Reused
+			t.lock.Unlock()
+		},
+	}
+	return httptrace.WithClientTrace(ctx, trace), func(err error) {
+		t.lock.Lock()
+		defer t.lock.Unlock()
+		t.setup.Measured = true
+		if err == nil {
+			t.setup.FirstWrite = time.Since(start)
+		}
+	}
+}
+
+// connectionSetup returns the measured setup
+func (t *connectionTrace) connectionSetup() ConnectionSetup {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.setup
+}
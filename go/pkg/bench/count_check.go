@@ -0,0 +1,34 @@
+package bench
+
+// CountCheck compares counts of identical loads written by the V1 and V2 clients into separate measurements,
+// each counted by the Count of its writer, InfluxQL of WriterV1 and the query of WriterV2 by WriterConfig.CountLang
+type CountCheck struct {
+	Expected int
+	V1       Result
+	V2       Result
+}
+
+// Agree tells whether both counting methods found the same count of points
+func (c CountCheck) Agree() bool {
+	return c.V1.Total == c.V2.Total
+}
+
+// RunCountCheck writes the load by the CLIENT_GO_V1 and CLIENT_GO_V2 writers, one after the other, into measurements
+// derived from config.MeasurementName and counts both. The Writer and SkipCount of config are not used.
+func RunCountCheck(writerConfig WriterConfig, config Config) (CountCheck, error) {
+	config.SkipCount = false
+	results, err := RunAll([]string{"CLIENT_GO_V1", "CLIENT_GO_V2"}, writerConfig, config)
+	if err != nil {
+		return CountCheck{}, err
+	}
+	var check CountCheck
+	for _, result := range results {
+		check.Expected = result.Result.Expected
+		if result.WriterType == "CLIENT_GO_V1" {
+			check.V1 = result.Result
+		} else {
+			check.V2 = result.Result
+		}
+	}
+	return check, nil
+}
@@ -0,0 +1,68 @@
+package bench
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// rangeCounter is implemented by writers that count points of a time range
+type rangeCounter interface {
+	timeQuerier
+	// countRange counts points with timestamps from start inclusive to stop exclusive in nanoseconds
+	countRange(measurementName string, start int64, stop int64) (int, error)
+}
+
+// timeWindows splits the time range from first to last inclusive into at most shards contiguous windows,
+// window i spans from windows[i] inclusive to windows[i+1] exclusive
+func timeWindows(first int64, last int64, shards int) []int64 {
+	span := last - first + 1
+	if int64(shards) > span {
+		shards = int(span)
+	}
+	windows := make([]int64, shards+1)
+	for i := range windows {
+		// span*i/shards without overflow of span*i
+		windows[i] = first + span/int64(shards)*int64(i) + span%int64(shards)*int64(i)/int64(shards)
+	}
+	return windows
+}
+
+// shardedCount counts the points of the measurement by CountTimeShards concurrent queries of the windows
+// of the time range of the points and sums the counts, it returns the times of the queries of the windows too
+func shardedCount(config Config, measurementName string) (int, []time.Duration, error) {
+	counter, ok := config.Writer.(rangeCounter)
+	if !ok {
+		return 0, nil, fmt.Errorf("the writer doesn't count time ranges")
+	}
+	if writer, ok := config.Writer.(flusher); ok {
+		writer.Flush()
+	}
+	first, last, err := counter.timeRange(measurementName)
+	if err != nil {
+		return 0, nil, err
+	}
+	windows := timeWindows(first, last, config.CountTimeShards)
+	counts := make([]int, len(windows)-1)
+	times := make([]time.Duration, len(windows)-1)
+	errs := make([]error, len(windows)-1)
+	var wg sync.WaitGroup
+	for i := range counts {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			start := time.Now()
+			counts[i], errs[i] = counter.countRange(measurementName, windows[i], windows[i+1])
+			times[i] = time.Since(start)
+		}(i)
+	}
+	wg.Wait()
+	total := 0
+	for i, count := range counts {
+		if errs[i] != nil {
+			return 0, nil, fmt.Errorf("count of the window %v of %v: %v", i+1, len(counts), errs[i])
+		}
+		total += count
+	}
+	return total, times, nil
+}
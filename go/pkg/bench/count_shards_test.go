@@ -0,0 +1,49 @@
+package bench
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestTimeWindows(t *testing.T) {
+	tests := []struct {
+		name   string
+		first  int64
+		last   int64
+		shards int
+		want   []int64
+	}{
+		{"one shard", 0, 9, 1, []int64{0, 10}},
+		{"even split", 0, 9, 2, []int64{0, 5, 10}},
+		{"uneven split", 0, 9, 3, []int64{0, 3, 6, 10}},
+		{"offset range", 100, 199, 4, []int64{100, 125, 150, 175, 200}},
+		{"negative timestamps", -10, -1, 2, []int64{-10, -5, 0}},
+		{"shards as many as the span", 0, 2, 3, []int64{0, 1, 2, 3}},
+		{"shards more than the span", 0, 2, 5, []int64{0, 1, 2, 3}},
+		{"one timestamp", 100, 100, 4, []int64{100, 101}},
+		{"huge span", 0, math.MaxInt64 - 1, 2, []int64{0, math.MaxInt64 / 2, math.MaxInt64}},
+	}
+	for _, test := range tests {
+		if got := timeWindows(test.first, test.last, test.shards); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: timeWindows(%v, %v, %v) = %v, want %v", test.name, test.first, test.last, test.shards, got, test.want)
+		}
+	}
+}
+
+func TestTimeWindowsCover(t *testing.T) {
+	// the windows are contiguous, not empty and cover the range exactly
+	for span := int64(1); span <= 50; span++ {
+		for shards := 1; shards <= 60; shards++ {
+			windows := timeWindows(1000, 1000+span-1, shards)
+			if windows[0] != 1000 || windows[len(windows)-1] != 1000+span {
+				t.Fatalf("timeWindows of the span %v by %v shards = %v don't cover it", span, shards, windows)
+			}
+			for i := 1; i < len(windows); i++ {
+				if windows[i] <= windows[i-1] {
+					t.Fatalf("timeWindows of the span %v by %v shards = %v have an empty window", span, shards, windows)
+				}
+			}
+		}
+	}
+}
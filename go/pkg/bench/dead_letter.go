@@ -0,0 +1,55 @@
+package bench
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DeadLetters writes points of permanently failed writes as line protocol, every failed batch is preceded by
+// a comment line with its error, so the file can be inspected or written again. It is shared by the writers of a run.
+type DeadLetters struct {
+	lock    sync.Mutex
+	output  *bufio.Writer
+	batches int
+	points  int
+	err     error
+}
+
+func NewDeadLetters(output io.Writer) *DeadLetters {
+	return &DeadLetters{output: bufio.NewWriter(output)}
+}
+
+// add writes the lines of the batch that failed by err, every line ends by a newline, a nil DeadLetters writes nothing
+func (d *DeadLetters) add(lines []byte, points int, err error) {
+	if d == nil || len(lines) == 0 {
+		return
+	}
+	message := "unknown error"
+	if err != nil {
+		// the comment ends at the first newline
+		message = strings.ReplaceAll(err.Error(), "\n", " ")
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.err == nil {
+		if _, d.err = fmt.Fprintf(d.output, "# %s %v points failed: %s\n", time.Now().Format(time.RFC3339Nano), points, message); d.err == nil {
+			_, d.err = d.output.Write(lines)
+		}
+		d.batches++
+		d.points += points
+	}
+}
+
+// Flush writes the buffered lines and returns the counts of all written batches and points, or the first error of writing
+func (d *DeadLetters) Flush() (int, int, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.err == nil {
+		d.err = d.output.Flush()
+	}
+	return d.batches, d.points, d.err
+}
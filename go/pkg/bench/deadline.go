@@ -0,0 +1,43 @@
+package bench
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// runEnder is implemented by writers whose writes are bounded by the end of the run
+type runEnder interface {
+	runEnds(end time.Time)
+}
+
+// writeDeadline bounds every write by the write timeout and by the end of the run, whichever comes first
+type writeDeadline struct {
+	timeout time.Duration
+	// runEnd is the end of the run in Unix nanoseconds, zero until Run sets it, it is accessed atomically
+	runEnd int64
+}
+
+func (d *writeDeadline) runEnds(end time.Time) {
+	atomic.StoreInt64(&d.runEnd, end.UnixNano())
+}
+
+// context returns the context of a write starting now, writes started after the end of the run,
+// like the final flush, are bounded by the timeout only
+func (d *writeDeadline) context(parent context.Context) (context.Context, context.CancelFunc) {
+	now := time.Now()
+	var deadline time.Time
+	if d.timeout > 0 {
+		deadline = now.Add(d.timeout)
+	}
+	if end := atomic.LoadInt64(&d.runEnd); end > 0 {
+		runEnd := time.Unix(0, end)
+		if now.Before(runEnd) && (deadline.IsZero() || runEnd.Before(deadline)) {
+			deadline = runEnd
+		}
+	}
+	if deadline.IsZero() {
+		return context.WithCancel(parent)
+	}
+	return context.WithDeadline(parent, deadline)
+}
@@ -0,0 +1,128 @@
+package bench
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// ErrorStats counts outcomes of failed writes by the WriterConfig.OnError policy
+type ErrorStats struct {
+	Policy string
+	// Requeued counts points put back for a later attempt
+	Requeued int64
+	// Dropped counts discarded points
+	Dropped int64
+	// Aborted tells whether a failed write stopped the run, AbortError is the error of the write
+	Aborted    bool
+	AbortError error
+}
+
+// ErrorReporter is implemented by writers that apply the OnError policy
+type ErrorReporter interface {
+	ErrorStats() ErrorStats
+}
+
+// aborter is implemented by writers that can stop the run, the channel is closed when they do
+type aborter interface {
+	aborted() <-chan bool
+}
+
+// errorPolicy applies the OnError policy ("requeue", "drop" or "abort") to failed writes
+type errorPolicy struct {
+	policy    string
+	requeued  int64
+	dropped   int64
+	abortOnce sync.Once
+	abort     chan bool
+	abortErr  error
+}
+
+func newErrorPolicy(policy string) *errorPolicy {
+	if policy == "" {
+		policy = "drop"
+	}
+	return &errorPolicy{policy: policy, abort: make(chan bool)}
+}
+
+// failed counts the points of a failed write, it returns true when the points have to be requeued
+func (e *errorPolicy) failed(points int, err error) bool {
+	switch e.policy {
+	case "requeue":
+		atomic.AddInt64(&e.requeued, int64(points))
+		return true
+	case "abort":
+		e.abortOnce.Do(func() {
+			e.abortErr = err
+			close(e.abort)
+		})
+	}
+	atomic.AddInt64(&e.dropped, int64(points))
+	return false
+}
+
+func (e *errorPolicy) aborted() <-chan bool {
+	return e.abort
+}
+
+func (e *errorPolicy) stats() ErrorStats {
+	stats := ErrorStats{
+		Policy:   e.policy,
+		Requeued: atomic.LoadInt64(&e.requeued),
+		Dropped:  atomic.LoadInt64(&e.dropped),
+	}
+	select {
+	case <-e.abort:
+		stats.Aborted = true
+		stats.AbortError = e.abortErr
+	default:
+	}
+	return stats
+}
+
+// mergeErrorStats sums the outcomes of the writers that apply the OnError policy, the run is aborted
+// when any of them aborted it
+func mergeErrorStats(writers []Writer) ErrorStats {
+	var stats ErrorStats
+	for _, writer := range writers {
+		reporter, ok := writer.(ErrorReporter)
+		if !ok {
+			continue
+		}
+		writerStats := reporter.ErrorStats()
+		if writerStats.Policy == "" {
+			continue
+		}
+		stats.Policy = writerStats.Policy
+		stats.Requeued += writerStats.Requeued
+		stats.Dropped += writerStats.Dropped
+		if writerStats.Aborted && !stats.Aborted {
+			stats.Aborted = true
+			stats.AbortError = writerStats.AbortError
+		}
+	}
+	return stats
+}
+
+// mergeAborted returns the channel closed when any of the writers aborts the run, it is nil when none of them can
+func mergeAborted(writers []Writer) <-chan bool {
+	var channels []<-chan bool
+	for _, writer := range writers {
+		if writer, ok := writer.(aborter); ok {
+			if channel := writer.aborted(); channel != nil {
+				channels = append(channels, channel)
+			}
+		}
+	}
+	if len(channels) == 0 {
+		return nil
+	}
+	merged := make(chan bool)
+	var once sync.Once
+	for _, channel := range channels {
+		go func(channel <-chan bool) {
+			<-channel
+			once.Do(func() { close(merged) })
+		}(channel)
+	}
+	return merged
+}
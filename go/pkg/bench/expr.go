@@ -0,0 +1,221 @@
+package bench
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ValueExpr is an arithmetic expression generating values of numeric fields, like "sin(t/10)*50+50",
+// "random(0,100)" or "counter". It supports numbers, + - * / %, parentheses, the variables t and counter
+// (the iteration of the point, one per point of a series) and pi, and the functions sin, cos, abs, sqrt, floor,
+// min, max and random(low, high).
+type ValueExpr struct {
+	source string
+	root   exprNode
+}
+
+// exprNode evaluates a part of the expression for the iteration
+type exprNode func(iteration float64) float64
+
+// ParseValueExpr parses the expression
+func ParseValueExpr(source string) (*ValueExpr, error) {
+	parser := &exprParser{source: source}
+	root, err := parser.expr()
+	if err == nil && parser.skipSpaces() < len(source) {
+		err = parser.errorf("unexpected %q", source[parser.position:])
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ValueExpr{source: source, root: root}, nil
+}
+
+func (e *ValueExpr) String() string {
+	return e.source
+}
+
+// eval evaluates the expression for the iteration
+func (e *ValueExpr) eval(iteration int) float64 {
+	return e.root(float64(iteration))
+}
+
+// exprParser is a recursive descent parser of ValueExpr
+type exprParser struct {
+	source   string
+	position int
+}
+
+func (p *exprParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("expression %q at %v: %s", p.source, p.position, fmt.Sprintf(format, args...))
+}
+
+// skipSpaces skips white space and returns the position
+func (p *exprParser) skipSpaces() int {
+	for p.position < len(p.source) && p.source[p.position] == ' ' {
+		p.position++
+	}
+	return p.position
+}
+
+// next returns the next operator or bracket without consuming it, it is 0 at the end
+func (p *exprParser) next() byte {
+	if p.skipSpaces() >= len(p.source) {
+		return 0
+	}
+	return p.source[p.position]
+}
+
+// expr parses the sum of terms
+func (p *exprParser) expr() (exprNode, error) {
+	left, err := p.term()
+	for err == nil && (p.next() == '+' || p.next() == '-') {
+		operator := p.source[p.position]
+		p.position++
+		var right exprNode
+		if right, err = p.term(); err != nil {
+			break
+		}
+		a, b := left, right
+		if operator == '+' {
+			left = func(i float64) float64 { return a(i) + b(i) }
+		} else {
+			left = func(i float64) float64 { return a(i) - b(i) }
+		}
+	}
+	return left, err
+}
+
+// term parses the product of unary expressions
+func (p *exprParser) term() (exprNode, error) {
+	left, err := p.unary()
+	for err == nil && (p.next() == '*' || p.next() == '/' || p.next() == '%') {
+		operator := p.source[p.position]
+		p.position++
+		var right exprNode
+		if right, err = p.unary(); err != nil {
+			break
+		}
+		a, b := left, right
+		switch operator {
+		case '*':
+			left = func(i float64) float64 { return a(i) * b(i) }
+		case '/':
+			left = func(i float64) float64 { return a(i) / b(i) }
+		default:
+			left = func(i float64) float64 { return math.Mod(a(i), b(i)) }
+		}
+	}
+	return left, err
+}
+
+func (p *exprParser) unary() (exprNode, error) {
+	if p.next() == '-' {
+		p.position++
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(i float64) float64 { return -operand(i) }, nil
+	}
+	return p.primary()
+}
+
+// primary parses a number, a variable, a call of a function or an expression in parentheses
+func (p *exprParser) primary() (exprNode, error) {
+	switch next := p.next(); {
+	case next == 0:
+		return nil, p.errorf("unexpected end")
+	case next == '(':
+		p.position++
+		inner, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ')' {
+			return nil, p.errorf("missing )")
+		}
+		p.position++
+		return inner, nil
+	case next == '.' || unicode.IsDigit(rune(next)):
+		start := p.position
+		for p.position < len(p.source) && (p.source[p.position] == '.' || unicode.IsDigit(rune(p.source[p.position]))) {
+			p.position++
+		}
+		value, err := strconv.ParseFloat(p.source[start:p.position], 64)
+		if err != nil {
+			return nil, p.errorf("invalid number %q", p.source[start:p.position])
+		}
+		return func(float64) float64 { return value }, nil
+	case unicode.IsLetter(rune(next)):
+		start := p.position
+		for p.position < len(p.source) && unicode.IsLetter(rune(p.source[p.position])) {
+			p.position++
+		}
+		name := strings.ToLower(p.source[start:p.position])
+		if p.next() == '(' {
+			return p.call(name)
+		}
+		switch name {
+		case "t", "counter":
+			return func(i float64) float64 { return i }, nil
+		case "pi":
+			return func(float64) float64 { return math.Pi }, nil
+		}
+		return nil, p.errorf("unknown variable %q", name)
+	default:
+		return nil, p.errorf("unexpected %q", string(next))
+	}
+}
+
+// exprFunctions are the functions of ValueExpr by their count of arguments
+var exprFunctions = map[string]struct {
+	arguments int
+	apply     func(args []float64) float64
+}{
+	"sin":    {1, func(args []float64) float64 { return math.Sin(args[0]) }},
+	"cos":    {1, func(args []float64) float64 { return math.Cos(args[0]) }},
+	"abs":    {1, func(args []float64) float64 { return math.Abs(args[0]) }},
+	"sqrt":   {1, func(args []float64) float64 { return math.Sqrt(args[0]) }},
+	"floor":  {1, func(args []float64) float64 { return math.Floor(args[0]) }},
+	"min":    {2, func(args []float64) float64 { return math.Min(args[0], args[1]) }},
+	"max":    {2, func(args []float64) float64 { return math.Max(args[0], args[1]) }},
+	"random": {2, func(args []float64) float64 { return args[0] + rand.Float64()*(args[1]-args[0]) }},
+}
+
+// call parses the arguments of the function after its name
+func (p *exprParser) call(name string) (exprNode, error) {
+	function, ok := exprFunctions[name]
+	if !ok {
+		return nil, p.errorf("unknown function %q", name)
+	}
+	p.position++
+	var arguments []exprNode
+	for p.next() != ')' {
+		if len(arguments) > 0 {
+			if p.next() != ',' {
+				return nil, p.errorf("missing , or )")
+			}
+			p.position++
+		}
+		argument, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		arguments = append(arguments, argument)
+	}
+	p.position++
+	if len(arguments) != function.arguments {
+		return nil, p.errorf("%s takes %v arguments, not %v", name, function.arguments, len(arguments))
+	}
+	return func(i float64) float64 {
+		args := make([]float64, len(arguments))
+		for j, argument := range arguments {
+			args[j] = argument(i)
+		}
+		return function.apply(args)
+	}, nil
+}
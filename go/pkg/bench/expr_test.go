@@ -0,0 +1,86 @@
+package bench
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseValueExpr(t *testing.T) {
+	tests := []struct {
+		source    string
+		iteration int
+		want      float64
+	}{
+		{"42", 0, 42},
+		{".5", 0, 0.5},
+		{"t", 7, 7},
+		{"counter * 2", 7, 14},
+		{"1 + 2 * 3", 0, 7},
+		{"(1 + 2) * 3", 0, 9},
+		{"10 - 4 - 3", 0, 3},
+		{"12 / 3 / 2", 0, 2},
+		{"t % 5", 12, 2},
+		{"-t", 3, -3},
+		{"--2", 0, 2},
+		{"2 * -3", 0, -6},
+		{"PI", 0, math.Pi},
+		{"sin(pi / 2) * 50", 0, 50},
+		{"cos(0)", 0, 1},
+		{"abs(-4) + sqrt(9) + floor(2.7)", 0, 9},
+		{"min(t, 10) + max(t, 10)", 3, 13},
+		{"max(min(t, 5), 2)", 9, 5},
+		{"  t  ", 4, 4},
+	}
+	for _, test := range tests {
+		expr, err := ParseValueExpr(test.source)
+		if err != nil {
+			t.Errorf("%q: %v", test.source, err)
+			continue
+		}
+		if got := expr.eval(test.iteration); math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("%q at %v = %v, want %v", test.source, test.iteration, got, test.want)
+		}
+		if expr.String() != test.source {
+			t.Errorf("%q: String() = %q", test.source, expr.String())
+		}
+	}
+}
+
+func TestParseValueExprRandom(t *testing.T) {
+	expr, err := ParseValueExpr("random(2, 3) + t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		if got := expr.eval(i); got < float64(i)+2 || got >= float64(i)+3 {
+			t.Fatalf("random(2, 3) + t at %v = %v, want [%v, %v)", i, got, i+2, i+3)
+		}
+	}
+}
+
+func TestParseValueExprErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"   ",
+		"1 +",
+		"* 2",
+		"(1 + 2",
+		"1 + 2)",
+		"1 2",
+		"1..2",
+		"x",
+		"t2",
+		"foo(1)",
+		"sin()",
+		"sin(1, 2)",
+		"random(1)",
+		"min(1 2)",
+		"min(1,",
+		"1 # 2",
+	}
+	for _, source := range tests {
+		if expr, err := ParseValueExpr(source); err == nil {
+			t.Errorf("%q: no error, parsed %v", source, expr)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package bench
+
+import (
+	"runtime"
+	"time"
+)
+
+// GoroutineSampleInterval is the period of sampling the count of goroutines
+const GoroutineSampleInterval = 100 * time.Millisecond
+
+// GoroutineStats is the count of goroutines sampled during the load, it includes the threads of the load
+// and the goroutines of the writers and their clients
+type GoroutineStats struct {
+	Samples int
+	// Start is the count before the threads of the load started
+	Start int
+	Peak  int
+	Avg   float64
+}
+
+// goroutineSampler samples runtime.NumGoroutine at an interval
+type goroutineSampler struct {
+	stop  chan bool
+	done  chan bool
+	stats GoroutineStats
+}
+
+func startGoroutineSampler(interval time.Duration) *goroutineSampler {
+	s := &goroutineSampler{stop: make(chan bool), done: make(chan bool)}
+	s.stats.Start = runtime.NumGoroutine()
+	go func() {
+		defer close(s.done)
+		total := 0
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				if s.stats.Samples > 0 {
+					s.stats.Avg = float64(total) / float64(s.stats.Samples)
+				}
+				return
+			case <-ticker.C:
+				count := runtime.NumGoroutine()
+				s.stats.Samples++
+				total += count
+				if count > s.stats.Peak {
+					s.stats.Peak = count
+				}
+			}
+		}
+	}()
+	return s
+}
+
+// finish stops the sampling and returns the sampled counts
+func (s *goroutineSampler) finish() GoroutineStats {
+	close(s.stop)
+	<-s.done
+	return s.stats
+}
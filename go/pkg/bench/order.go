@@ -0,0 +1,84 @@
+package bench
+
+import "fmt"
+
+// timeQuerier is implemented by writers that query timestamps of the written points
+type timeQuerier interface {
+	// seriesTimes returns the timestamps of points of the series id sorted by time
+	seriesTimes(measurementName string, id int) ([]int64, error)
+	// timeRange returns the timestamps of the first and the last point of all series
+	timeRange(measurementName string) (int64, int64, error)
+}
+
+// OrderReport is the outcome of VerifyOrder
+type OrderReport struct {
+	// Series is the count of the sampled series and Points the count of their points
+	Series int
+	Points int
+	// Anomalies describe timestamps of the sampled series that don't strictly increase
+	Anomalies []string
+	// First and Last are the timestamps of the first and the last point of all series
+	First int64
+	Last  int64
+	// Counted is the count of the points of all series
+	Counted int
+	// Missing is how many ticks of the ordered clock between First and Last have no point, it is negative
+	// when more points than ticks were counted
+	Missing int64
+}
+
+// Ok tells whether no anomalies and no missing points were found
+func (r OrderReport) Ok() bool {
+	return len(r.Anomalies) == 0 && r.Missing == 0
+}
+
+// maxOrderAnomalies limits the anomalies described in the report
+const maxOrderAnomalies = 10
+
+// VerifyOrder checks points written with PointOptions.OrderedClock, timestamps of sampleCount series out of
+// seriesCount ones have to strictly increase and the points of all series have to fill every tick of the clock
+// between the first and the last point
+func VerifyOrder(writer Writer, measurementName string, seriesCount int, sampleCount int) (OrderReport, error) {
+	querier, ok := writer.(timeQuerier)
+	if !ok {
+		return OrderReport{}, fmt.Errorf("the writer doesn't support verification of the order")
+	}
+	if sampleCount > seriesCount {
+		sampleCount = seriesCount
+	}
+	var report OrderReport
+	anomalies := 0
+	for i := 0; i < sampleCount; i++ {
+		// the sampled series are spread evenly over all series
+		id := 1 + i*seriesCount/sampleCount
+		times, err := querier.seriesTimes(measurementName, id)
+		if err != nil {
+			return report, err
+		}
+		report.Series++
+		report.Points += len(times)
+		for j := 1; j < len(times); j++ {
+			if times[j] <= times[j-1] {
+				if anomalies < maxOrderAnomalies {
+					report.Anomalies = append(report.Anomalies, fmt.Sprintf("series %v: timestamp %v after %v", id, times[j], times[j-1]))
+				}
+				anomalies++
+			}
+		}
+	}
+	if anomalies > maxOrderAnomalies {
+		report.Anomalies = append(report.Anomalies, fmt.Sprintf("... %v more", anomalies-maxOrderAnomalies))
+	}
+	first, last, err := querier.timeRange(measurementName)
+	if err != nil {
+		return report, err
+	}
+	report.First, report.Last = first, last
+	if report.Counted, err = writer.Count(measurementName); err != nil {
+		return report, err
+	}
+	if report.Counted > 0 {
+		report.Missing = last - first + 1 - int64(report.Counted)
+	}
+	return report, nil
+}
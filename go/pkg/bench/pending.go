@@ -0,0 +1,137 @@
+package bench
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PendingStats are outcomes of the bound of points pending in the asynchronous V2 write API
+type PendingStats struct {
+	MaxPending int
+	// Blocked counts points that waited for a flush of the write API, BlockedTime is the total time of the waits
+	Blocked     int64
+	BlockedTime time.Duration
+	// Dropped counts points discarded because the bound was reached
+	Dropped int64
+}
+
+// PendingReporter is implemented by writers that bound their pending points
+type PendingReporter interface {
+	PendingStats() PendingStats
+}
+
+// pendingGuard bounds points handed to the asynchronous write API since its last flush, the points
+// beyond the bound wait for a flush or they are dropped until a flush started in the background completes
+type pendingGuard struct {
+	max      int
+	drop     bool
+	lock     sync.Mutex
+	locks    *lockTimer
+	pending  int
+	flushing bool
+	// background waits for the flush started in the background
+	background sync.WaitGroup
+	stats      PendingStats
+}
+
+// admit tells whether the next point can be written, flush is called to make room when the point waits.
+// The flush runs outside of the lock, so the callers that wait for it are all counted as blocked and
+// the batches sent meanwhile reset the pending points.
+func (g *pendingGuard) admit(flush func()) bool {
+	g.locks.lock(&g.lock)
+	if g.pending < g.max {
+		g.pending++
+		g.lock.Unlock()
+		return true
+	}
+	if g.drop {
+		g.stats.Dropped++
+		if !g.flushing {
+			g.flushing = true
+			g.background.Add(1)
+			go func() {
+				defer g.background.Done()
+				flush()
+				g.flushed()
+			}()
+		}
+		g.lock.Unlock()
+		return false
+	}
+	g.stats.Blocked++
+	g.lock.Unlock()
+	start := time.Now()
+	flush()
+	g.locks.lock(&g.lock)
+	g.stats.BlockedTime += time.Since(start)
+	// the flush sent the points pending before it, the point of the caller is the first one after it
+	g.pending = 1
+	g.lock.Unlock()
+	return true
+}
+
+// wait waits for the flush started in the background
+func (g *pendingGuard) wait() {
+	g.background.Wait()
+}
+
+// flushed resets the pending points after a flush of the write API
+func (g *pendingGuard) flushed() {
+	g.lock.Lock()
+	g.pending = 0
+	g.flushing = false
+	g.lock.Unlock()
+}
+
+// sent resets the pending points after the write API sent a batch by itself, or failed to send it
+func (g *pendingGuard) sent() {
+	g.lock.Lock()
+	g.pending = 0
+	g.lock.Unlock()
+}
+
+// sentTransport calls sent after every write request of the write API, successful or not
+type sentTransport struct {
+	transport http.RoundTripper
+	sent      func()
+}
+
+func (t *sentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	response, err := t.transport.RoundTrip(req)
+	if strings.HasSuffix(req.URL.Path, "/api/v2/write") {
+		t.sent()
+	}
+	return response, err
+}
+
+func (g *pendingGuard) pendingStats() PendingStats {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	stats := g.stats
+	stats.MaxPending = g.max
+	return stats
+}
+
+// mergePendingStats sums the outcomes of the bounds of the writers, every writer has the same bound
+func mergePendingStats(writers []Writer) PendingStats {
+	var stats PendingStats
+	for _, writer := range writers {
+		if reporter, ok := writer.(PendingReporter); ok {
+			stats = addPendingStats(stats, reporter.PendingStats())
+		}
+	}
+	return stats
+}
+
+// addPendingStats returns the sum of the outcomes of two bounds
+func addPendingStats(stats PendingStats, other PendingStats) PendingStats {
+	if other.MaxPending > stats.MaxPending {
+		stats.MaxPending = other.MaxPending
+	}
+	stats.Blocked += other.Blocked
+	stats.BlockedTime += other.BlockedTime
+	stats.Dropped += other.Dropped
+	return stats
+}
@@ -0,0 +1,79 @@
+package bench
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// pipelineCall is a Write call of the "pipeline" Config.Model
+type pipelineCall struct {
+	id              int
+	measurementName string
+	iterations      []int
+}
+
+// generatePipeline sends the Write calls of all seconds of the load into calls without pacing, the calls of every
+// thread id of the "burst" model are interleaved, so the written points are the same in both models
+func generatePipeline(stopExecution <-chan bool, calls chan<- pipelineCall, config Config) {
+	defer close(calls)
+	for i := 1; i <= config.SecondsCount; i++ {
+		start := i * config.LineProtocolsCount
+		end := start + config.LineProtocolsCount
+		for j := start; j < end; {
+			callEnd := j + config.PointsPerCall
+			if callEnd > end {
+				callEnd = end
+			}
+			name := config.MeasurementName
+			if switchEvery := config.MeasurementSwitchEvery; switchEvery > 0 {
+				// one call doesn't cross the switch of the measurement
+				if next := (j/switchEvery + 1) * switchEvery; next < callEnd {
+					callEnd = next
+				}
+				if (j/switchEvery)%2 == 1 {
+					name = AlternateMeasurement(config.MeasurementName)
+				}
+			}
+			for id := 1; id <= config.ThreadsCount; id++ {
+				iterations := make([]int, 0, callEnd-j)
+				for k := j; k < callEnd; k++ {
+					iterations = append(iterations, k)
+				}
+				select {
+				case calls <- pipelineCall{id, name, iterations}:
+				case <-stopExecution:
+					return
+				}
+			}
+			j = callEnd
+		}
+	}
+}
+
+// consumePipeline writes the calls until there are no more calls or the execution stops
+func consumePipeline(wg *sync.WaitGroup, stopExecution <-chan bool, calls <-chan pipelineCall, influx Writer, latencies *[]time.Duration, written *int64, writes *timeline, clientDelay time.Duration) {
+	defer wg.Done()
+	for {
+		var call pipelineCall
+		var ok bool
+		select {
+		case <-stopExecution:
+			return
+		case call, ok = <-calls:
+			if !ok {
+				return
+			}
+		}
+		writeStart := time.Now()
+		if clientDelay > 0 {
+			time.Sleep(clientDelay)
+		}
+		influx.Write(call.id, call.measurementName, call.iterations)
+		if writes != nil {
+			writes.add(len(call.iterations))
+		}
+		*latencies = append(*latencies, time.Since(writeStart))
+		atomic.AddInt64(written, int64(len(call.iterations)))
+	}
+}
@@ -0,0 +1,530 @@
+package bench
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const paddingAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// PointOptions configures generation of the written points
+type PointOptions struct {
+	// PaddingBytes is the size of the random "padding" field, the field is added only for positive PaddingBytes
+	PaddingBytes int
+	// TimestampScale is the divisor applied to the generated timestamp before sending, it has to be positive
+	TimestampScale int64
+	// InterleaveSeries mixes series of all SeriesCount sensors into points written by one thread
+	InterleaveSeries bool
+	SeriesCount      int
+	// FieldKeyChurn adds a field whose key changes every FieldKeyChurn iterations, the field is added only when positive
+	FieldKeyChurn int
+	// OrderedClock is shared by all threads, every point gets the next nanosecond of it as its timestamp,
+	// so timestamps are globally unique and increasing. Timestamps are derived from iterations when it is nil.
+	OrderedClock *int64
+	// RunTag is the value of the "run" tag added to every point, the tag is added only when not empty
+	RunTag string
+	// DuplicateRate is the fraction of iterations that reuse the series and the timestamp of the previous
+	// iteration, so the point overwrites an already written one. It can't be combined with OrderedClock.
+	DuplicateRate float64
+	// IntFields writes the "temperature" field as an integer, serialized with the "i" suffix by the raw writers,
+	// instead of the default string
+	IntFields bool
+	// UintFields adds the unsigned integer field UintFieldKey, serialized with the "u" suffix by the raw writers
+	UintFields bool
+	// BoolFields adds the boolean field BoolFieldKey
+	BoolFields bool
+	// Fields replace the "temperature" field by the named fields of explicit types, see ParseFieldSpec
+	Fields []FieldSpec
+	// Precision is the unit of written timestamps, time.Nanosecond, time.Microsecond, time.Millisecond
+	// or time.Second, generated timestamps are truncated to it. Zero means time.Nanosecond.
+	Precision time.Duration
+	// FloatFormat formats values of float fields serialized by the raw writers, see ParseFloatFormat
+	FloatFormat FloatFormat
+}
+
+// FloatFormat is the format of float field values in line protocol, the zero value is the shortest
+// representation without an exponent
+type FloatFormat struct {
+	verb      byte
+	precision int
+}
+
+// ParseFloatFormat parses the fmt verb like "%g", "%.2f", "%.15g" or "%e", the precision defaults to 6
+// for %f and %e and to the shortest representation for %g like in fmt. An empty format is the zero FloatFormat.
+func ParseFloatFormat(format string) (FloatFormat, error) {
+	if format == "" {
+		return FloatFormat{}, nil
+	}
+	if len(format) < 2 || format[0] != '%' {
+		return FloatFormat{}, fmt.Errorf("float format %q is not a fmt verb like %%g or %%.2f", format)
+	}
+	parsed := FloatFormat{verb: format[len(format)-1], precision: 6}
+	switch parsed.verb {
+	case 'f', 'e':
+	case 'g':
+		parsed.precision = -1
+	default:
+		return FloatFormat{}, fmt.Errorf("unsupported verb of the float format %q, only f, e and g are supported", format)
+	}
+	if precision := format[1 : len(format)-1]; precision != "" {
+		value, err := strconv.Atoi(strings.TrimPrefix(precision, "."))
+		if err != nil || precision[0] != '.' || value < 0 {
+			return FloatFormat{}, fmt.Errorf("invalid precision of the float format %q", format)
+		}
+		parsed.precision = value
+	}
+	return parsed, nil
+}
+
+// append appends the formatted value
+func (f FloatFormat) append(buffer []byte, value float64) []byte {
+	if f.verb == 0 {
+		return strconv.AppendFloat(buffer, value, 'f', -1, 64)
+	}
+	return strconv.AppendFloat(buffer, value, f.verb, f.precision, 64)
+}
+
+// ParsePrecision parses the precision of written timestamps, "ns", "us", "ms" or "s"
+func ParsePrecision(precision string) (time.Duration, error) {
+	switch precision {
+	case "ns":
+		return time.Nanosecond, nil
+	case "us":
+		return time.Microsecond, nil
+	case "ms":
+		return time.Millisecond, nil
+	case "s":
+		return time.Second, nil
+	default:
+		return 0, fmt.Errorf("unsupported precision: %v", precision)
+	}
+}
+
+// precision returns the unit of written timestamps
+func (o PointOptions) precision() time.Duration {
+	if o.Precision <= 0 {
+		return time.Nanosecond
+	}
+	return o.Precision
+}
+
+// PrecisionV1 returns the precision parameter of InfluxDB 1.x writes, it is empty for nanoseconds
+func (o PointOptions) PrecisionV1() string {
+	switch o.precision() {
+	case time.Microsecond:
+		return "u"
+	case time.Millisecond:
+		return "ms"
+	case time.Second:
+		return "s"
+	default:
+		return ""
+	}
+}
+
+// PrecisionV2 returns the precision parameter of InfluxDB 2.x writes
+func (o PointOptions) PrecisionV2() string {
+	switch o.precision() {
+	case time.Microsecond:
+		return "us"
+	case time.Millisecond:
+		return "ms"
+	case time.Second:
+		return "s"
+	default:
+		return "ns"
+	}
+}
+
+// UintFieldKey and BoolFieldKey are the keys of the fields of PointOptions.UintFields and PointOptions.BoolFields
+const (
+	UintFieldKey = "counter"
+	BoolFieldKey = "active"
+)
+
+// FieldSpec is a named field of the generated points, Type is "float", "int", "uint", "bool" or "string"
+type FieldSpec struct {
+	Key  string
+	Type string
+	// Values are cycled by the iterations of a string field, the string is unique for every point when empty
+	Values []string
+	// Expr generates the values of a numeric field, they are random or unique when it is nil
+	Expr *ValueExpr
+}
+
+// ParseFieldSpec parses the fields like "temp:float,count:int,total:uint,ok:bool,name:string", values cycled
+// by a string field follow its type like "status:string=ok|warn|error" and the expression generating a numeric field
+// follows its type like "temp:float=sin(t/10)*50", see ValueExpr
+func ParseFieldSpec(spec string) ([]FieldSpec, error) {
+	var fields []FieldSpec
+	keys := make(map[string]bool)
+	for _, part := range strings.Split(spec, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		keyType := strings.SplitN(part, ":", 2)
+		if len(keyType) != 2 || strings.TrimSpace(keyType[0]) == "" {
+			return nil, fmt.Errorf("field %q is not in the key:type format", part)
+		}
+		field := FieldSpec{Key: strings.TrimSpace(keyType[0]), Type: strings.TrimSpace(keyType[1])}
+		if typeValues := strings.SplitN(field.Type, "=", 2); len(typeValues) == 2 {
+			switch field.Type = strings.TrimSpace(typeValues[0]); field.Type {
+			case "string":
+				if field.Values = ParseStringValues(typeValues[1], "|"); len(field.Values) == 0 {
+					return nil, fmt.Errorf("no values of the field %q", field.Key)
+				}
+			case "float", "int", "uint":
+				expr, err := ParseValueExpr(strings.TrimSpace(typeValues[1]))
+				if err != nil {
+					return nil, fmt.Errorf("field %q: %v", field.Key, err)
+				}
+				field.Expr = expr
+			default:
+				return nil, fmt.Errorf("values of the field %q of the type %q, only string and numeric fields have values", field.Key, field.Type)
+			}
+		}
+		switch field.Type {
+		case "float", "int", "uint", "bool", "string":
+		default:
+			return nil, fmt.Errorf("unsupported type %q of the field %q", field.Type, field.Key)
+		}
+		if keys[field.Key] {
+			return nil, fmt.Errorf("duplicate field %q", field.Key)
+		}
+		keys[field.Key] = true
+		fields = append(fields, field)
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no fields in %q", spec)
+	}
+	return fields, nil
+}
+
+// ParseFields parses the fields of the spec by ParseFieldSpec, the string fields without own values cycle
+// the comma-separated stringValues and the numeric fields without own expression are generated by valueExpr.
+// An empty spec means no fields, stringValues and valueExpr need fields of their types then.
+func ParseFields(spec string, stringValues string, valueExpr string) ([]FieldSpec, error) {
+	if spec == "" {
+		if stringValues != "" || valueExpr != "" {
+			return nil, fmt.Errorf("stringFieldValues and valueExpr need fields of fieldSpec")
+		}
+		return nil, nil
+	}
+	fields, err := ParseFieldSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	if stringValues != "" {
+		values := ParseStringValues(stringValues, ",")
+		stringFields := 0
+		for i := range fields {
+			if fields[i].Type == "string" {
+				stringFields++
+				if len(fields[i].Values) == 0 {
+					fields[i].Values = values
+				}
+			}
+		}
+		if len(values) == 0 || stringFields == 0 {
+			return nil, fmt.Errorf("stringFieldValues %q need string fields of fieldSpec", stringValues)
+		}
+	}
+	if valueExpr != "" {
+		expr, err := ParseValueExpr(valueExpr)
+		if err != nil {
+			return nil, err
+		}
+		numericFields := 0
+		for i := range fields {
+			if fields[i].Type == "float" || fields[i].Type == "int" || fields[i].Type == "uint" {
+				numericFields++
+				if fields[i].Expr == nil {
+					fields[i].Expr = expr
+				}
+			}
+		}
+		if numericFields == 0 {
+			return nil, fmt.Errorf("valueExpr %q needs numeric fields of fieldSpec", valueExpr)
+		}
+	}
+	return fields, nil
+}
+
+// ParseStringValues splits the values by the separator, values are trimmed and empty values are skipped
+func ParseStringValues(values string, separator string) []string {
+	var parsed []string
+	for _, value := range strings.Split(values, separator) {
+		if value = strings.TrimSpace(value); value != "" {
+			parsed = append(parsed, value)
+		}
+	}
+	return parsed
+}
+
+// ParseTags parses comma-separated key=value tags, like "region=eu,host=a", the id and run tags of the points
+// can't be redefined
+func ParseTags(tags string) (map[string]string, error) {
+	parsed := map[string]string{}
+	for _, tag := range ParseStringValues(tags, ",") {
+		parts := strings.SplitN(tag, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
+			return nil, fmt.Errorf("tag %q is not in the key=value format", tag)
+		}
+		key := strings.TrimSpace(parts[0])
+		if key == "id" || key == "run" {
+			return nil, fmt.Errorf("tag %q redefines the %s tag of the points", tag, key)
+		}
+		parsed[key] = strings.TrimSpace(parts[1])
+	}
+	return parsed, nil
+}
+
+// value generates the value of the field for the iteration
+func (f FieldSpec) value(iteration int) interface{} {
+	if f.Expr != nil {
+		value := f.Expr.eval(iteration)
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			// InfluxDB doesn't store values that are not finite
+			value = 0
+		}
+		switch f.Type {
+		case "int":
+			return int64(value)
+		case "uint":
+			return uint64(math.Max(value, 0))
+		default:
+			return value
+		}
+	}
+	switch f.Type {
+	case "float":
+		return rand.Float64() * 100
+	case "int":
+		return time.Now().UnixNano()
+	case "uint":
+		return uint64(time.Now().UnixNano())
+	case "bool":
+		return iteration%2 == 0
+	default:
+		if len(f.Values) > 0 {
+			return f.Values[iteration%len(f.Values)]
+		}
+		return fmt.Sprintf("%v", time.Now().UnixNano())
+	}
+}
+
+// tags creates the tags of the point written by the sensor id in the iteration
+func (o PointOptions) tags(id int, iteration int) map[string]string {
+	tags := map[string]string{"id": fmt.Sprintf("%v", o.seriesId(id, iteration))}
+	if o.RunTag != "" {
+		tags["run"] = o.RunTag
+	}
+	return tags
+}
+
+// fields creates the fields of the point written in the iteration
+func (o PointOptions) fields(iteration int) map[string]interface{} {
+	fields := make(map[string]interface{}, len(o.Fields)+1)
+	if len(o.Fields) > 0 {
+		for _, field := range o.Fields {
+			fields[field.Key] = field.value(iteration)
+		}
+	} else if o.IntFields {
+		fields["temperature"] = time.Now().UnixNano()
+	} else {
+		fields["temperature"] = fmt.Sprintf("%v", time.Now().UnixNano())
+	}
+	if o.UintFields {
+		fields[UintFieldKey] = uint64(iteration)
+	}
+	if o.BoolFields {
+		fields[BoolFieldKey] = iteration%2 == 0
+	}
+	if o.PaddingBytes > 0 {
+		padding := make([]byte, o.PaddingBytes)
+		for i := range padding {
+			padding[i] = paddingAlphabet[rand.Intn(len(paddingAlphabet))]
+		}
+		fields["padding"] = string(padding)
+	}
+	if o.FieldKeyChurn > 0 {
+		fields[ChurnFieldKey(iteration, o.FieldKeyChurn)] = rand.Float64()
+	}
+	return fields
+}
+
+// ChurnFieldKey returns the key of the churning field of the iteration, like "temperature_0", "temperature_1", ...
+func ChurnFieldKey(iteration int, churn int) string {
+	return "temperature_" + strconv.Itoa(iteration/churn)
+}
+
+// seriesId returns the "id" tag of the point written by the sensor id in the iteration. Interleaved series
+// rotate the id by the iteration, so the consecutive points of one thread belong to different series.
+func (o PointOptions) seriesId(id int, iteration int) int {
+	if !o.InterleaveSeries {
+		return id
+	}
+	return (id-1+o.keyIteration(iteration))%o.SeriesCount + 1
+}
+
+// duplicate tells whether the point of the iteration reuses the key of the previous iteration,
+// the decision is derived from the iteration so it is the same in all writers
+func (o PointOptions) duplicate(iteration int) bool {
+	if o.DuplicateRate <= 0 {
+		return false
+	}
+	// multiplicative hashing spreads consecutive iterations evenly over [0, 1)
+	return float64(uint32(iteration)*2654435761)/(1<<32) < o.DuplicateRate
+}
+
+// keyIteration returns the iteration whose series and timestamp the point of the iteration gets
+func (o PointOptions) keyIteration(iteration int) int {
+	for iteration > 0 && o.duplicate(iteration) {
+		iteration--
+	}
+	return iteration
+}
+
+// UniqueKeys returns how many points of threadsCount threads, each writing iterations first to last into one
+// measurement, remain after duplicates and after TimestampScale and Precision collapse timestamps of a series into
+// one. The timestamps of OrderedClock depend on the scheduling of the threads, they are assumed unique.
+func (o PointOptions) UniqueKeys(threadsCount int, first int, last int) int {
+	if o.OrderedClock != nil {
+		return threadsCount * (last - first + 1)
+	}
+	// the timestamp of a key is the key divided by the truncation, keys of a thread don't decrease
+	truncation := o.TimestampScale * int64(o.precision())
+	if truncation < 1 {
+		truncation = 1
+	}
+	var bucketKeys []int
+	unique := 0
+	previousKey, previousBucket := -1, int64(-1)
+	for iteration := first; iteration <= last+1; iteration++ {
+		bucket := int64(-2)
+		key := -1
+		if iteration <= last {
+			key = o.keyIteration(iteration)
+			bucket = int64(key) / truncation
+		}
+		if bucket != previousBucket && len(bucketKeys) > 0 {
+			unique += o.bucketSeries(threadsCount, bucketKeys)
+			bucketKeys = bucketKeys[:0]
+		}
+		if key >= 0 && key != previousKey {
+			bucketKeys = append(bucketKeys, key)
+		}
+		previousKey, previousBucket = key, bucket
+	}
+	return unique
+}
+
+// TimeWindow returns the range of timestamps in nanoseconds, from start inclusive to stop exclusive, of the points
+// threadsCount threads write as iterations first to last, including the truncation by Precision. It has to be called
+// before the points are written, the window of OrderedClock starts at its current tick.
+func (o PointOptions) TimeWindow(threadsCount int, first int, last int) (int64, int64) {
+	if o.OrderedClock != nil {
+		start := atomic.LoadInt64(o.OrderedClock) / o.TimestampScale
+		return start, start + int64(threadsCount*(last-first+1)) + 1
+	}
+	unit := int64(o.precision())
+	start := int64(o.keyIteration(first)) / o.TimestampScale / unit * unit
+	stop := int64(last)/o.TimestampScale/unit*unit + unit
+	return start, stop
+}
+
+// bucketSeries returns how many distinct series threadsCount threads write the increasing keys of one timestamp into
+func (o PointOptions) bucketSeries(threadsCount int, keys []int) int {
+	if !o.InterleaveSeries {
+		// every thread writes its own series
+		return threadsCount
+	}
+	if threadsCount >= o.SeriesCount {
+		return o.SeriesCount
+	}
+	// the thread id writes the key into the series (id-1+key) % SeriesCount + 1
+	series := make([]bool, o.SeriesCount)
+	count := 0
+	for _, key := range keys {
+		for id := 0; id < threadsCount; id++ {
+			if index := (id + key) % o.SeriesCount; !series[index] {
+				series[index] = true
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// timestamp returns the timestamp integer sent for the iteration, or the next tick of the ordered clock
+func (o PointOptions) timestamp(iteration int) int64 {
+	if o.OrderedClock != nil {
+		// the clock advances by the scale, so scaled timestamps stay unique
+		return atomic.AddInt64(o.OrderedClock, o.TimestampScale) / o.TimestampScale
+	}
+	return int64(o.keyIteration(iteration)) / o.TimestampScale
+}
+
+// writtenTimestamp returns the timestamp of the iteration in the unit of Precision, as it is serialized
+// into line protocol by the benchmark
+func (o PointOptions) writtenTimestamp(iteration int) int64 {
+	return o.timestamp(iteration) / int64(o.precision())
+}
+
+var lineProtocolStringEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// appendLineProtocol appends the point serialized into line protocol, fields are serialized sorted by key
+// and the run tag is added only when not empty, the names are escaped like line protocol requires
+func appendLineProtocol(buffer []byte, measurementName string, id int, run string, fields map[string]interface{}, floats FloatFormat, timestamp int64) []byte {
+	buffer = append(buffer, lineProtocolMeasurementEscaper.Replace(measurementName)...)
+	buffer = append(buffer, ",id="...)
+	buffer = strconv.AppendInt(buffer, int64(id), 10)
+	if run != "" {
+		buffer = append(buffer, ",run="...)
+		buffer = append(buffer, lineProtocolKeyEscaper.Replace(run)...)
+	}
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for i, key := range keys {
+		if i == 0 {
+			buffer = append(buffer, ' ')
+		} else {
+			buffer = append(buffer, ',')
+		}
+		buffer = append(buffer, lineProtocolKeyEscaper.Replace(key)...)
+		buffer = append(buffer, '=')
+		switch value := fields[key].(type) {
+		case string:
+			buffer = append(buffer, '"')
+			buffer = append(buffer, lineProtocolStringEscaper.Replace(value)...)
+			buffer = append(buffer, '"')
+		case int:
+			buffer = strconv.AppendInt(buffer, int64(value), 10)
+			buffer = append(buffer, 'i')
+		case int64:
+			buffer = strconv.AppendInt(buffer, value, 10)
+			buffer = append(buffer, 'i')
+		case uint64:
+			buffer = strconv.AppendUint(buffer, value, 10)
+			buffer = append(buffer, 'u')
+		case float64:
+			buffer = floats.append(buffer, value)
+		case bool:
+			buffer = strconv.AppendBool(buffer, value)
+		default:
+			buffer = append(buffer, fmt.Sprintf("\"%v\"", value)...)
+		}
+	}
+	buffer = append(buffer, ' ')
+	buffer = strconv.AppendInt(buffer, timestamp, 10)
+	return append(buffer, '\n')
+}
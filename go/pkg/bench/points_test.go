@@ -0,0 +1,162 @@
+package bench
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseFieldSpec(t *testing.T) {
+	tests := []struct {
+		spec string
+		want []FieldSpec
+	}{
+		{"temp:float", []FieldSpec{{Key: "temp", Type: "float"}}},
+		{
+			"temp:float,count:int,total:uint,ok:bool,name:string",
+			[]FieldSpec{{Key: "temp", Type: "float"}, {Key: "count", Type: "int"}, {Key: "total", Type: "uint"}, {Key: "ok", Type: "bool"}, {Key: "name", Type: "string"}},
+		},
+		{" temp : float , , count:int,", []FieldSpec{{Key: "temp", Type: "float"}, {Key: "count", Type: "int"}}},
+		{"status:string=ok|warn||error", []FieldSpec{{Key: "status", Type: "string", Values: []string{"ok", "warn", "error"}}}},
+	}
+	for _, test := range tests {
+		got, err := ParseFieldSpec(test.spec)
+		if err != nil {
+			t.Errorf("%q: %v", test.spec, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q = %+v, want %+v", test.spec, got, test.want)
+		}
+	}
+}
+
+func TestParseFieldSpecExpr(t *testing.T) {
+	fields, err := ParseFieldSpec("temp:float=t*2,count:int")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fields[0].Expr == nil || fields[0].Expr.eval(3) != 6 {
+		t.Errorf("temp:float=t*2 has the expression %v", fields[0].Expr)
+	}
+	if fields[1].Expr != nil {
+		t.Errorf("count:int has the expression %v", fields[1].Expr)
+	}
+}
+
+func TestParseFieldSpecErrors(t *testing.T) {
+	tests := []string{
+		"",
+		" , ",
+		"temp",
+		":float",
+		"temp:",
+		"temp:double",
+		"temp:float,temp:int",
+		"ok:bool=true",
+		"name:string=",
+		"name:string=|",
+		"temp:float=sin(",
+		"temp:float=",
+	}
+	for _, spec := range tests {
+		if fields, err := ParseFieldSpec(spec); err == nil {
+			t.Errorf("%q: no error, parsed %+v", spec, fields)
+		}
+	}
+}
+
+func TestParseFields(t *testing.T) {
+	fields, err := ParseFields("a:string,b:string=x,c:float,d:int=1,e:bool", "p, q", "t+1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fields[0].Values, []string{"p", "q"}) || !reflect.DeepEqual(fields[1].Values, []string{"x"}) {
+		t.Errorf("values of the string fields are %v and %v", fields[0].Values, fields[1].Values)
+	}
+	if fields[2].Expr == nil || fields[2].Expr.String() != "t+1" || fields[3].Expr.String() != "1" || fields[4].Expr != nil {
+		t.Errorf("expressions of the fields are %v, %v and %v", fields[2].Expr, fields[3].Expr, fields[4].Expr)
+	}
+	if fields, err := ParseFields("", "", ""); err != nil || fields != nil {
+		t.Errorf("empty spec = %v, %v, want no fields", fields, err)
+	}
+	invalid := []struct {
+		spec         string
+		stringValues string
+		valueExpr    string
+	}{
+		{"", "a,b", ""},
+		{"", "", "t"},
+		{"temp:float", "a,b", ""},
+		{"name:string", " , ", ""},
+		{"name:string", "", "t"},
+		{"temp:float", "", "t +"},
+		{"temp", "", ""},
+	}
+	for _, test := range invalid {
+		if fields, err := ParseFields(test.spec, test.stringValues, test.valueExpr); err == nil {
+			t.Errorf("%q, %q, %q: no error, parsed %+v", test.spec, test.stringValues, test.valueExpr, fields)
+		}
+	}
+}
+
+func TestUniqueKeys(t *testing.T) {
+	clock := int64(0)
+	tests := []struct {
+		name         string
+		options      PointOptions
+		threadsCount int
+		first        int
+		last         int
+		want         int
+	}{
+		{"distinct timestamps", PointOptions{TimestampScale: 1}, 2, 10, 19, 20},
+		{"empty range", PointOptions{TimestampScale: 1}, 2, 10, 9, 0},
+		{"reversed range", PointOptions{TimestampScale: 1}, 2, 10, 5, 0},
+		{"scale collapses all", PointOptions{TimestampScale: 10}, 2, 10, 19, 2},
+		{"scale collapses into buckets", PointOptions{TimestampScale: 10}, 2, 5, 24, 6},
+		{"microsecond precision", PointOptions{TimestampScale: 1, Precision: time.Microsecond}, 3, 0, 1999, 6},
+		{"duplicates", PointOptions{TimestampScale: 1, DuplicateRate: 0.5}, 1, 1, 10, 5},
+		{"interleaved series", PointOptions{TimestampScale: 1, InterleaveSeries: true, SeriesCount: 3}, 1, 0, 5, 6},
+		{"interleaved series collapsed", PointOptions{TimestampScale: 10, InterleaveSeries: true, SeriesCount: 3}, 1, 0, 5, 3},
+		{"interleaved series of all threads", PointOptions{TimestampScale: 10, InterleaveSeries: true, SeriesCount: 3}, 4, 0, 5, 3},
+		{"ordered clock", PointOptions{TimestampScale: 1000, OrderedClock: &clock}, 2, 10, 19, 20},
+	}
+	for _, test := range tests {
+		if got := test.options.UniqueKeys(test.threadsCount, test.first, test.last); got != test.want {
+			t.Errorf("%s: UniqueKeys(%v, %v, %v) = %v, want %v", test.name, test.threadsCount, test.first, test.last, got, test.want)
+		}
+	}
+}
+
+func TestParseFloatFormat(t *testing.T) {
+	tests := []struct {
+		format string
+		value  float64
+		want   string
+	}{
+		{"", 1.5, "1.5"},
+		{"", 1e21, "1000000000000000000000"},
+		{"%g", 0.1, "0.1"},
+		{"%.3g", 3.14159, "3.14"},
+		{"%f", 2, "2.000000"},
+		{"%.2f", 3.14159, "3.14"},
+		{"%.0f", 2.5, "2"},
+		{"%e", 1500, "1.500000e+03"},
+	}
+	for _, test := range tests {
+		format, err := ParseFloatFormat(test.format)
+		if err != nil {
+			t.Errorf("%q: %v", test.format, err)
+			continue
+		}
+		if got := string(format.append(nil, test.value)); got != test.want {
+			t.Errorf("%q of %v = %q, want %q", test.format, test.value, got, test.want)
+		}
+	}
+	for _, format := range []string{"%", "g", "%d", "%.f", "%2f", "%.-1f", "%.xf", "%%"} {
+		if parsed, err := ParseFloatFormat(format); err == nil {
+			t.Errorf("%q: no error, parsed %+v", format, parsed)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package bench
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// AllWriterTypes are the InfluxDB writers run by the ALL type
+var AllWriterTypes = []string{"CLIENT_GO_V1", "CLIENT_GO_V2", "HTTP_GO_V1", "HTTP_GO_V2"}
+
+// ModeTypes are the types that run their own loads or several writers instead of a single run of one writer,
+// the features of single runs are not supported by them
+var ModeTypes = []string{"ALL", "AUTOTUNE", "BATCH_SWEEP", "COMPARE_V2", "COUNT_CHECK", "REPLAY", "SELFTEST", "SERIALIZE"}
+
+// IsModeType tells whether writerType is one of ModeTypes
+func IsModeType(writerType string) bool {
+	for _, modeType := range ModeTypes {
+		if writerType == modeType {
+			return true
+		}
+	}
+	return false
+}
+
+// RankResult is the outcome of one writer of RunAll
+type RankResult struct {
+	WriterType string
+	Result     Result
+}
+
+// Throughput returns the rate of written points per second
+func (r RankResult) Throughput(pointsPerCall int) float64 {
+	return float64(len(r.Result.Samples)*pointsPerCall) / r.Result.Elapsed.Seconds()
+}
+
+// RunAll runs writers of all writerTypes back to back with identical parameters of the load, each into its own
+// measurement derived from config.MeasurementName, and returns their results ranked by throughput, the fastest first.
+// The Writer of config is not used.
+func RunAll(writerTypes []string, writerConfig WriterConfig, config Config) ([]RankResult, error) {
+	results := make([]RankResult, 0, len(writerTypes))
+	for _, writerType := range writerTypes {
+		if !config.Quiet {
+			fmt.Println("Writing by", writerType, "...")
+		}
+		writer := NewWriter(writerType, "", writerConfig)
+		load := config
+		load.Writer = writer
+		load.MeasurementName = config.MeasurementName + "_" + strings.ToLower(writerType)
+		result, err := Run(load)
+		if err != nil {
+			writer.Close()
+			return nil, fmt.Errorf("%s writer failed: %v", writerType, err)
+		}
+		if err := writer.Close(); err != nil {
+			return nil, fmt.Errorf("closing of the %s writer failed: %v", writerType, err)
+		}
+		results = append(results, RankResult{WriterType: writerType, Result: result})
+		if !config.Quiet {
+			fmt.Println()
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Throughput(config.PointsPerCall) > results[j].Throughput(config.PointsPerCall)
+	})
+	return results, nil
+}
@@ -0,0 +1,87 @@
+package bench
+
+import (
+	"sync"
+	"time"
+)
+
+// ReadStats are the count queries executed concurrently with the load by Config.ConcurrentReads
+type ReadStats struct {
+	Readers int
+	Queries int
+	Errors  int
+	// Elapsed is the time the readers were querying
+	Elapsed time.Duration
+	// Samples are sorted latencies of the successful queries
+	Samples []time.Duration
+}
+
+// QueriesPerSecond returns the throughput of the successful queries
+func (s ReadStats) QueriesPerSecond() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(len(s.Samples)) / s.Elapsed.Seconds()
+}
+
+// bufferedReader is implemented by writers whose Count sends their buffered points first
+type bufferedReader interface {
+	// readCount counts the points without sending the buffered points
+	readCount(measurementName string) (int, error)
+}
+
+// readCount counts the points of the measurement, the buffered points of the writer are left intact,
+// so that the queries don't change the batches of the load
+func readCount(writer Writer, measurementName string) (int, error) {
+	if reader, ok := writer.(bufferedReader); ok {
+		return reader.readCount(measurementName)
+	}
+	return writer.Count(measurementName)
+}
+
+// concurrentReads executes the count query of the measurement by the readers in a loop until finish
+type concurrentReads struct {
+	stop      chan bool
+	wg        sync.WaitGroup
+	start     time.Time
+	latencies [][]time.Duration
+	errors    []int
+}
+
+func startConcurrentReads(writer Writer, measurementName string, readers int) *concurrentReads {
+	r := &concurrentReads{stop: make(chan bool), start: time.Now(), latencies: make([][]time.Duration, readers), errors: make([]int, readers)}
+	for i := 0; i < readers; i++ {
+		r.wg.Add(1)
+		go func(i int) {
+			defer r.wg.Done()
+			for {
+				select {
+				case <-r.stop:
+					return
+				default:
+				}
+				queryStart := time.Now()
+				if _, err := readCount(writer, measurementName); err != nil {
+					r.errors[i]++
+					// a failing server is not hammered by the loop
+					time.Sleep(100 * time.Millisecond)
+					continue
+				}
+				r.latencies[i] = append(r.latencies[i], time.Since(queryStart))
+			}
+		}(i)
+	}
+	return r
+}
+
+// finish stops the readers, waits for their running queries and returns their stats
+func (r *concurrentReads) finish() ReadStats {
+	close(r.stop)
+	r.wg.Wait()
+	stats := ReadStats{Readers: len(r.latencies), Elapsed: time.Since(r.start), Samples: MergeLatencies(r.latencies)}
+	for _, errors := range r.errors {
+		stats.Errors += errors
+	}
+	stats.Queries = len(stats.Samples) + stats.Errors
+	return stats
+}
@@ -0,0 +1,119 @@
+package bench
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Spread is the mean and the sample standard deviation of a value measured by repetitions
+type Spread struct {
+	Mean   float64
+	Stddev float64
+}
+
+func spreadOf(values []float64) Spread {
+	if len(values) == 0 {
+		return Spread{}
+	}
+	var spread Spread
+	for _, value := range values {
+		spread.Mean += value
+	}
+	spread.Mean /= float64(len(values))
+	if len(values) > 1 {
+		for _, value := range values {
+			spread.Stddev += (value - spread.Mean) * (value - spread.Mean)
+		}
+		spread.Stddev = math.Sqrt(spread.Stddev / float64(len(values)-1))
+	}
+	return spread
+}
+
+// RepeatSummary summarizes results of RunRepeated
+type RepeatSummary struct {
+	Repetitions int
+	// Throughput is the rate of written points per second
+	Throughput Spread
+	// Percentiles are the latencies at Config.Percentiles in nanoseconds
+	Percentiles []Spread
+	// Loss is the percentage of expected points that were not counted, it is nil without counts
+	Loss *Spread
+	// FailedRequests is the percentage of failed write requests, it is nil for writers without WriteStats
+	FailedRequests *Spread
+}
+
+// RunRepeated runs the load repetitions times, every repetition by its own writer created by newWriter
+// into its own measurement derived from config.MeasurementName and with its own run tag, when there is one.
+// The writers are closed after their repetition, the Writer of config is not used.
+func RunRepeated(newWriter func(WriterConfig) Writer, writerConfig WriterConfig, config Config, repetitions int) ([]Result, error) {
+	results := make([]Result, 0, repetitions)
+	for i := 1; i <= repetitions; i++ {
+		if !config.Quiet {
+			fmt.Printf("Repetition %v/%v ...\n", i, repetitions)
+		}
+		repetitionConfig := writerConfig
+		if writerConfig.Points.RunTag != "" {
+			repetitionConfig.Points.RunTag = writerConfig.Points.RunTag + "_" + strconv.Itoa(i)
+		}
+		writer := newWriter(repetitionConfig)
+		load := config
+		load.Writer = writer
+		load.MeasurementName = config.MeasurementName + "_" + strconv.Itoa(i)
+		result, err := Run(load)
+		if err != nil {
+			writer.Close()
+			return nil, fmt.Errorf("repetition %v failed: %v", i, err)
+		}
+		if err := writer.Close(); err != nil {
+			return nil, fmt.Errorf("closing of the writer of repetition %v failed: %v", i, err)
+		}
+		results = append(results, result)
+		if !config.Quiet {
+			fmt.Println()
+		}
+	}
+	return results, nil
+}
+
+// ResultThroughput returns the rate of points written per second by the result
+func ResultThroughput(result Result, pointsPerCall int) float64 {
+	return float64(len(result.Samples)*pointsPerCall) / result.Elapsed.Seconds()
+}
+
+// SummarizeRepeated summarizes the results of repetitions of the load with the percentiles
+func SummarizeRepeated(results []Result, pointsPerCall int, percentiles []float64) RepeatSummary {
+	summary := RepeatSummary{Repetitions: len(results)}
+	var throughputs, losses, failures []float64
+	latencies := make([][]float64, len(percentiles))
+	for _, result := range results {
+		throughputs = append(throughputs, ResultThroughput(result, pointsPerCall))
+		for i, percentile := range percentiles {
+			latency := time.Duration(0)
+			if len(result.Samples) > 0 {
+				latency = LatencyPercentile(result.Samples, percentile)
+			}
+			latencies[i] = append(latencies[i], float64(latency))
+		}
+		if result.Counted {
+			losses = append(losses, 100-result.Rate)
+		}
+		if result.Stats != nil && result.Stats.Batches > 0 {
+			failures = append(failures, float64(result.Stats.FailedBatches)/float64(result.Stats.Batches)*100)
+		}
+	}
+	summary.Throughput = spreadOf(throughputs)
+	for _, values := range latencies {
+		summary.Percentiles = append(summary.Percentiles, spreadOf(values))
+	}
+	if len(losses) == len(results) {
+		loss := spreadOf(losses)
+		summary.Loss = &loss
+	}
+	if len(failures) == len(results) {
+		failed := spreadOf(failures)
+		summary.FailedRequests = &failed
+	}
+	return summary
+}
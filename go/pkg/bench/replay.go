@@ -0,0 +1,215 @@
+package bench
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CsvMapping maps columns of a CSV file with a header row to the measurement, tags, fields and timestamp of points
+type CsvMapping struct {
+	Measurement string
+	Tags        []string
+	Fields      []string
+	// Time is the column of the timestamp in RFC3339 or integer nanoseconds, empty means the time of reading
+	Time string
+}
+
+// ParseCsvMapping parses the mapping like "measurement=cpu;tags=host,region;fields=usage,idle;time=ts"
+func ParseCsvMapping(spec string) (CsvMapping, error) {
+	var mapping CsvMapping
+	for _, part := range strings.Split(spec, ";") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		keyValue := strings.SplitN(part, "=", 2)
+		if len(keyValue) != 2 {
+			return mapping, fmt.Errorf("mapping %q is not in the key=value format", part)
+		}
+		value := strings.TrimSpace(keyValue[1])
+		switch strings.TrimSpace(keyValue[0]) {
+		case "measurement":
+			mapping.Measurement = value
+		case "tags":
+			mapping.Tags = splitColumns(value)
+		case "fields":
+			mapping.Fields = splitColumns(value)
+		case "time":
+			mapping.Time = value
+		default:
+			return mapping, fmt.Errorf("unknown mapping key %q", keyValue[0])
+		}
+	}
+	if mapping.Measurement == "" {
+		return mapping, fmt.Errorf("mapping has no measurement")
+	}
+	if len(mapping.Fields) == 0 {
+		return mapping, fmt.Errorf("mapping has no fields")
+	}
+	return mapping, nil
+}
+
+func splitColumns(value string) []string {
+	var columns []string
+	for _, column := range strings.Split(value, ",") {
+		if column = strings.TrimSpace(column); column != "" {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+var (
+	lineProtocolKeyEscaper         = strings.NewReplacer(`,`, `\,`, `=`, `\=`, ` `, `\ `)
+	lineProtocolMeasurementEscaper = strings.NewReplacer(`,`, `\,`, ` `, `\ `)
+)
+
+// ReadCsv converts rows of the CSV file into line protocol by the mapping, numeric and boolean values
+// become float and boolean fields, other values string fields, empty values are skipped
+func ReadCsv(path string, mapping CsvMapping) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	reader := csv.NewReader(file)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%s has no header: %v", path, err)
+	}
+	index := make(map[string]int, len(header))
+	for i, column := range header {
+		index[strings.TrimSpace(column)] = i
+	}
+	for _, column := range append(append(append([]string(nil), mapping.Tags...), mapping.Fields...), mapping.Time) {
+		if _, ok := index[column]; column != "" && !ok {
+			return nil, fmt.Errorf("%s has no column %q", path, column)
+		}
+	}
+	var lines []string
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return lines, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, err := csvLine(record, index, mapping)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, row, err)
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+}
+
+// csvLine serializes the CSV record into line protocol, it returns an empty line for a record without fields
+func csvLine(record []string, index map[string]int, mapping CsvMapping) (string, error) {
+	var line strings.Builder
+	line.WriteString(lineProtocolMeasurementEscaper.Replace(mapping.Measurement))
+	for _, tag := range mapping.Tags {
+		if value := record[index[tag]]; value != "" {
+			line.WriteString("," + lineProtocolKeyEscaper.Replace(tag) + "=" + lineProtocolKeyEscaper.Replace(value))
+		}
+	}
+	fields := 0
+	for _, field := range mapping.Fields {
+		value := record[index[field]]
+		if value == "" {
+			continue
+		}
+		if fields == 0 {
+			line.WriteByte(' ')
+		} else {
+			line.WriteByte(',')
+		}
+		fields++
+		line.WriteString(lineProtocolKeyEscaper.Replace(field) + "=")
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			line.WriteString(strconv.FormatFloat(number, 'f', -1, 64))
+		} else if boolean, err := strconv.ParseBool(value); err == nil {
+			line.WriteString(strconv.FormatBool(boolean))
+		} else {
+			line.WriteString(`"` + lineProtocolStringEscaper.Replace(value) + `"`)
+		}
+	}
+	if fields == 0 {
+		return "", nil
+	}
+	timestamp := time.Now().UnixNano()
+	if mapping.Time != "" {
+		value := record[index[mapping.Time]]
+		if nanos, err := strconv.ParseInt(value, 10, 64); err == nil {
+			timestamp = nanos
+		} else if parsed, err := time.Parse(time.RFC3339Nano, value); err == nil {
+			timestamp = parsed.UnixNano()
+		} else {
+			return "", fmt.Errorf("unsupported timestamp %q", value)
+		}
+	}
+	line.WriteString(" " + strconv.FormatInt(timestamp, 10))
+	return line.String(), nil
+}
+
+// LineWriter is implemented by writers that write points already serialized into line protocol
+type LineWriter interface {
+	WriteLines(lines []string) error
+}
+
+// ReplayResult is the outcome of RunReplay
+type ReplayResult struct {
+	Lines   int
+	Batches int
+	// Failed counts batches whose write failed
+	Failed  int
+	Elapsed time.Duration
+}
+
+// RunReplay writes the lines by batches of batchSize lines, threadsCount threads take the batches in order
+func RunReplay(writer Writer, lines []string, batchSize int, threadsCount int) (ReplayResult, error) {
+	lineWriter, ok := writer.(LineWriter)
+	if !ok {
+		return ReplayResult{}, fmt.Errorf("the writer doesn't support replay")
+	}
+	batches := make(chan []string)
+	result := ReplayResult{Lines: len(lines)}
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < threadsCount; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				err := lineWriter.WriteLines(batch)
+				lock.Lock()
+				result.Batches++
+				if err != nil {
+					result.Failed++
+				}
+				lock.Unlock()
+			}
+		}()
+	}
+	for from := 0; from < len(lines); from += batchSize {
+		to := from + batchSize
+		if to > len(lines) {
+			to = len(lines)
+		}
+		batches <- lines[from:to]
+	}
+	close(batches)
+	wg.Wait()
+	if writer, ok := writer.(flusher); ok {
+		writer.Flush()
+	}
+	result.Elapsed = time.Since(start)
+	return result, nil
+}
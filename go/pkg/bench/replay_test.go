@@ -0,0 +1,53 @@
+package bench
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCsvMapping(t *testing.T) {
+	tests := []struct {
+		spec string
+		want CsvMapping
+	}{
+		{"measurement=cpu;fields=usage", CsvMapping{Measurement: "cpu", Fields: []string{"usage"}}},
+		{
+			"measurement=cpu;tags=host,region;fields=usage,idle;time=ts",
+			CsvMapping{Measurement: "cpu", Tags: []string{"host", "region"}, Fields: []string{"usage", "idle"}, Time: "ts"},
+		},
+		{
+			" fields = usage, ,idle ; measurement = cpu ;; tags= host, ",
+			CsvMapping{Measurement: "cpu", Tags: []string{"host"}, Fields: []string{"usage", "idle"}},
+		},
+		{"measurement=cpu;fields=usage;time=", CsvMapping{Measurement: "cpu", Fields: []string{"usage"}}},
+	}
+	for _, test := range tests {
+		got, err := ParseCsvMapping(test.spec)
+		if err != nil {
+			t.Errorf("%q: %v", test.spec, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q = %+v, want %+v", test.spec, got, test.want)
+		}
+	}
+}
+
+func TestParseCsvMappingErrors(t *testing.T) {
+	tests := []string{
+		"",
+		";",
+		"measurement=cpu",
+		"measurement=cpu;fields=",
+		"measurement=cpu;fields= , ",
+		"fields=usage",
+		"measurement=;fields=usage",
+		"measurement=cpu;fields=usage;host",
+		"measurement=cpu;fields=usage;columns=a",
+	}
+	for _, spec := range tests {
+		if mapping, err := ParseCsvMapping(spec); err == nil {
+			t.Errorf("%q: no error, parsed %+v", spec, mapping)
+		}
+	}
+}
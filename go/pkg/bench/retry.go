@@ -0,0 +1,79 @@
+package bench
+
+import (
+	"context"
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+// RetryBackoff is the delay before the first retry of a write request, it doubles with every next retry of the same
+// request up to MaxRetryBackoff, a random jitter of up to a half of the delay is added so that writers don't retry
+// in lockstep
+const (
+	RetryBackoff    = 100 * time.Millisecond
+	MaxRetryBackoff = 5 * time.Second
+)
+
+// RetryBudget caps the total count of retried write requests across all writers of a run
+type RetryBudget struct {
+	budget    int64
+	used      int64
+	abandoned int64
+}
+
+// RetryStats are retries used out of the budget and writes abandoned after the budget was exhausted
+type RetryStats struct {
+	Budget    int64
+	Used      int64
+	Abandoned int64
+}
+
+func NewRetryBudget(budget int64) *RetryBudget {
+	return &RetryBudget{budget: budget}
+}
+
+// take reserves one retry, the write is counted as abandoned when the budget is exhausted
+func (b *RetryBudget) take() bool {
+	if atomic.AddInt64(&b.used, 1) <= b.budget {
+		return true
+	}
+	atomic.AddInt64(&b.used, -1)
+	atomic.AddInt64(&b.abandoned, 1)
+	return false
+}
+
+func (b *RetryBudget) Stats() RetryStats {
+	return RetryStats{
+		Budget:    b.budget,
+		Used:      atomic.LoadInt64(&b.used),
+		Abandoned: atomic.LoadInt64(&b.abandoned),
+	}
+}
+
+// retryBackoff returns the delay before the retry following the given attempt, the first attempt is 1
+func retryBackoff(attempt int) time.Duration {
+	delay := RetryBackoff
+	for i := 1; i < attempt && delay < MaxRetryBackoff; i++ {
+		delay *= 2
+	}
+	if delay > MaxRetryBackoff {
+		delay = MaxRetryBackoff
+	}
+	return delay + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// sleepContext sleeps for the delay, it fails when the context is done first
+func sleepContext(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
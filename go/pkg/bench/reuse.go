@@ -0,0 +1,45 @@
+package bench
+
+import (
+	"strconv"
+	"sync"
+)
+
+// reusedLines caches the line protocol of the first point of every series without its timestamp, points written
+// with WriterConfig.ReusePoints are serialized once and only their timestamps vary, so their fields are static
+type reusedLines struct {
+	// lines are the lines ending by the space before the timestamp by seriesLine
+	lines sync.Map
+}
+
+type seriesLine struct {
+	measurementName string
+	series          int
+}
+
+// newReusedLines returns nil unless WriterConfig.ReusePoints
+func newReusedLines(config WriterConfig) *reusedLines {
+	if !config.ReusePoints {
+		return nil
+	}
+	return &reusedLines{}
+}
+
+// appendLine appends the point of the iteration serialized into line protocol, the point is generated by points
+// only for the first iteration of its series
+func (r *reusedLines) appendLine(buffer []byte, points PointOptions, id int, measurementName string, iteration int) []byte {
+	series := points.seriesId(id, iteration)
+	if r == nil {
+		return appendLineProtocol(buffer, measurementName, series, points.RunTag, points.fields(iteration), points.FloatFormat, points.writtenTimestamp(iteration))
+	}
+	key := seriesLine{measurementName, series}
+	prefix, ok := r.lines.Load(key)
+	if !ok {
+		line := appendLineProtocol(nil, measurementName, series, points.RunTag, points.fields(iteration), points.FloatFormat, 0)
+		// the zero timestamp and the newline are cut off
+		prefix, _ = r.lines.LoadOrStore(key, line[:len(line)-2])
+	}
+	buffer = append(buffer, prefix.([]byte)...)
+	buffer = strconv.AppendInt(buffer, points.writtenTimestamp(iteration), 10)
+	return append(buffer, '\n')
+}
@@ -0,0 +1,701 @@
+package bench
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Config configures the load driven by Run
+type Config struct {
+	// Writer receives points written by all threads
+	Writer             Writer
+	ThreadsCount       int
+	SecondsCount       int
+	MeasurementName    string
+	LineProtocolsCount int
+	PointsPerCall      int
+	// Expected is the count of distinct points of the load, zero means ThreadsCount*SecondsCount*LineProtocolsCount
+	Expected int
+	// SkipCount skips counting of the written points
+	SkipCount bool
+	// Baseline is the count of points found in the measurements before the load, it is subtracted from the counts
+	// of the load, see CountExisting
+	Baseline int
+	// CountDelay waits after the load before counting, so that ingestion and indexing catch up
+	CountDelay time.Duration
+	// CountStabilizeTimeout repeats the count every CountPollInterval until two counts in a row are the same, or until
+	// the timeout passes, zero counts once
+	CountStabilizeTimeout time.Duration
+	// CountTimeShards splits the counted time range into windows counted by concurrent queries, the counts of
+	// the windows are summed, zero or one counts by one query
+	CountTimeShards int
+	// Percentiles are the reported write latency percentiles, like 50, 90, 99 and 99.9
+	Percentiles []float64
+	// Quiet suppresses the progress output
+	Quiet bool
+	// ReportSchedLatency samples delays of goroutine wake-ups during the load
+	ReportSchedLatency bool
+	// ReportGoroutines samples the count of goroutines every GoroutineSampleInterval during the load
+	ReportGoroutines bool
+	// ReportAllocs reads the memory statistics before and after the load to report allocations per Write call
+	ReportAllocs bool
+	// ReportSyncOverhead measures the time the threads spend in the selects of the stop and in pacing, and the
+	// wait for locks of writers created with WriterConfig.ReportLockWait
+	ReportSyncOverhead bool
+	// RampDownSeconds continues the load for the given seconds with the count of points decreasing linearly to zero,
+	// the ramp-down is written into MeasurementName+"_rampdown" and it is excluded from latencies, Elapsed and counts
+	RampDownSeconds int
+	// CheckpointInterval counts the points in InfluxDB at every interval of the load and compares them with
+	// the points passed to the Writer so far, zero disables checkpoints
+	CheckpointInterval time.Duration
+	// MeasurementSwitchEvery switches the measurement of the points between MeasurementName and
+	// AlternateMeasurement(MeasurementName) every given iterations of a thread, points of both are counted,
+	// zero writes all points into MeasurementName
+	MeasurementSwitchEvery int
+	// Arrival paces Write calls of a thread, "uniform" writes the points of every second back to back and sleeps
+	// for a second, "poisson" spreads the calls of every second over it as arrivals of a Poisson process with the
+	// count of the second, so the delays between calls are random but every second writes its points. Empty means "uniform".
+	Arrival string
+	// Model coordinates the Write calls of the threads, "burst" threads write the points of every second back to back
+	// and wait for the next second, the "pipeline" generator goroutine passes the Write calls of all seconds by
+	// a channel to ThreadsCount workers that write continuously without pacing. Empty means "burst". The pipeline
+	// doesn't support Arrival and RampDownSeconds.
+	Model string
+	// ClientDelay is slept in every Write call of the load before the point is handed off to the writer, it simulates
+	// a slow application producing the points and it is included in the write latency
+	ClientDelay time.Duration
+	// ConcurrentReads executes the count query of MeasurementName by the given count of goroutines in a loop during
+	// the load, the buffered points of the writer are not sent by the queries, zero disables the reads
+	ConcurrentReads int
+	// TimelineBucket breaks the finished Write calls of the load, including the ramp-down, down by buckets of the given
+	// length, zero disables the timeline
+	TimelineBucket time.Duration
+}
+
+// Result is the outcome of Run
+type Result struct {
+	// Latencies are latencies of Write calls of each thread
+	Latencies [][]time.Duration
+	// Samples are sorted latencies of Write calls of all threads
+	Samples []time.Duration
+	// Percentiles are the latencies of Samples at Config.Percentiles
+	Percentiles []Percentile
+	// Elapsed is the time taken by the load including flushing of buffered points
+	Elapsed time.Duration
+	// Expected is the count of points written by all threads
+	Expected int
+	// Attempted is the count of points all threads actually passed to the Writer, it is smaller than Expected when
+	// the load stopped early, duplicates and collapsed timestamps are included in it
+	Attempted int64
+	// Counted tells whether Total was counted, it is false for Config.SkipCount
+	Counted bool
+	// Total is the count of points found in InfluxDB without Config.Baseline
+	Total int
+	// Baseline is Config.Baseline subtracted from Total
+	Baseline int
+	// Rate is Total in percents of Expected
+	Rate float64
+	// AttemptedRate is Total in percents of Attempted
+	AttemptedRate float64
+	CountTime     time.Duration
+	// CountPolls is the count of count queries taken until the count stabilized or the timeout passed
+	CountPolls int
+	// CountShardTimes are the query times of the windows of the last count by Config.CountTimeShards,
+	// the windows of the alternate measurement follow the ones of the measurement
+	CountShardTimes []time.Duration
+	// Stats are outcomes of write requests of writers that report them, it is nil for other writers
+	Stats *WriteStats
+	// Endpoints break the writes of WriterMulti down per endpoint, it is nil for other writers
+	Endpoints []EndpointResult
+	// Rotation counts token rotations and errors around them of writers that rotate tokens, it is nil for other writers
+	Rotation *RotationStats
+	// Connections counts the TCP connections dialed by the writer during the load, it is nil for writers that
+	// don't count them
+	Connections *int64
+	// ConnectionSetups are the cold starts of the writer, or of each writer of WriterMulti, that measure them
+	ConnectionSetups []ConnectionSetup
+	// Errors are outcomes of failed writes of writers applying the OnError policy, it is nil for other writers
+	Errors *ErrorStats
+	// Pending are outcomes of the bound of pending points of writers that bound them, it is nil for other writers
+	Pending *PendingStats
+	// Checkpoints are the counts taken at every Config.CheckpointInterval during the load
+	Checkpoints []Checkpoint
+	// Timeline are the writes finished within every Config.TimelineBucket of the load, it is nil without the bucket
+	Timeline []TimelineBucket
+	// SchedLatency is the scheduling delay sampled during the load, it is nil without Config.ReportSchedLatency
+	SchedLatency *SchedLatency
+	// Goroutines is the count of goroutines during the load, it is nil without Config.ReportGoroutines
+	Goroutines *GoroutineStats
+	// Allocs are the allocations during the load including flushing, it is nil without Config.ReportAllocs
+	Allocs *Allocs
+	// SyncOverhead is the synchronization of the threads, it is nil without Config.ReportSyncOverhead
+	SyncOverhead *SyncOverhead
+	// BatchSizes is the distribution of sizes of the sent batches, it is nil unless the writer draws them from a range
+	BatchSizes *BatchSizeStats
+	// Reads are the queries executed during the load, it is nil without Config.ConcurrentReads
+	Reads *ReadStats
+}
+
+// Percentile is the latency of Write calls at the percentile
+type Percentile struct {
+	Percentile float64
+	Latency    time.Duration
+}
+
+// Checkpoint compares the points passed to the Writer with the points counted in InfluxDB at a time of the load
+type Checkpoint struct {
+	// At is the time since the start of the load
+	At      time.Duration
+	Written int64
+	Counted int
+	// Err is the error of the count query, Counted is zero with an error
+	Err error
+}
+
+// Gap returns how many written points were not counted yet
+func (c Checkpoint) Gap() int64 {
+	return c.Written - int64(c.Counted)
+}
+
+// EndpointResult is the count of points written into one endpoint of WriterMulti
+type EndpointResult struct {
+	Url    string
+	Writes int64
+}
+
+// flusher is implemented by writers that buffer points, Flush writes the buffered points
+type flusher interface {
+	Flush()
+}
+
+// Run writes by ThreadsCount threads for SecondsCount seconds, every thread writes LineProtocolsCount points
+// each second by Write calls of PointsPerCall points. The written points are counted unless SkipCount is set,
+// the Writer is not closed.
+func Run(config Config) (Result, error) {
+	stopExecution := make(chan bool)
+	var stopOnce sync.Once
+	stop := func() { stopOnce.Do(func() { close(stopExecution) }) }
+	var wg sync.WaitGroup
+	wg.Add(config.ThreadsCount)
+
+	latencies := make([][]time.Duration, config.ThreadsCount)
+	var clocks []syncClock
+	if config.ReportSyncOverhead {
+		clocks = make([]syncClock, config.ThreadsCount)
+	}
+	// loadEnds are the times when the threads finished the measured load before their ramp-down
+	loadEnds := make([]time.Time, config.ThreadsCount)
+
+	var sampler *schedSampler
+	if config.ReportSchedLatency {
+		sampler = startSchedSampler(SchedLatencyInterval)
+	}
+	var goroutines *goroutineSampler
+	if config.ReportGoroutines {
+		goroutines = startGoroutineSampler(GoroutineSampleInterval)
+	}
+
+	var memStats runtime.MemStats
+	if config.ReportAllocs {
+		runtime.ReadMemStats(&memStats)
+	}
+
+	// written counts points passed to the Writer, it is updated atomically
+	var written int64
+
+	start := time.Now()
+	if writer, ok := config.Writer.(runEnder); ok {
+		// writes near the end of the run don't outlive it
+		writer.runEnds(start.Add(time.Duration(config.SecondsCount+config.RampDownSeconds) * time.Second))
+	}
+
+	var writes *timeline
+	if config.TimelineBucket > 0 {
+		writes = newTimeline(start, config.TimelineBucket, time.Duration(config.SecondsCount+config.RampDownSeconds)*time.Second)
+	}
+
+	if config.Model == "pipeline" {
+		calls := make(chan pipelineCall, config.ThreadsCount)
+		go generatePipeline(stopExecution, calls, config)
+		for i := 1; i <= config.ThreadsCount; i++ {
+			go consumePipeline(&wg, stopExecution, calls, config.Writer, &latencies[i-1], &written, writes, config.ClientDelay)
+		}
+	} else {
+		for i := 1; i <= config.ThreadsCount; i++ {
+			thread := loadThread{id: i, latencies: &latencies[i-1], loadEnd: &loadEnds[i-1], written: &written, writes: writes}
+			if clocks != nil {
+				thread.clock = &clocks[i-1]
+			}
+			go doLoad(&wg, stopExecution, config, thread)
+		}
+	}
+
+	var reads *concurrentReads
+	if config.ConcurrentReads > 0 {
+		reads = startConcurrentReads(config.Writer, config.MeasurementName, config.ConcurrentReads)
+	}
+
+	var checkpoints []Checkpoint
+	checkpointsDone := make(chan bool)
+	if config.CheckpointInterval > 0 {
+		go func() {
+			defer close(checkpointsDone)
+			ticker := time.NewTicker(config.CheckpointInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-stopExecution:
+					return
+				case <-ticker.C:
+				}
+				checkpoint := Checkpoint{At: time.Since(start), Written: atomic.LoadInt64(&written)}
+				checkpoint.Counted, checkpoint.Err = readCheckpoint(config)
+				checkpoint.Counted -= config.Baseline
+				checkpoints = append(checkpoints, checkpoint)
+				if !config.Quiet {
+					if checkpoint.Err != nil {
+						fmt.Printf("\ncheckpoint %v: written %v, count failed: %v\n", checkpoint.At.Round(time.Second), checkpoint.Written, checkpoint.Err)
+					} else {
+						fmt.Printf("\ncheckpoint %v: written %v, counted %v, gap %v\n", checkpoint.At.Round(time.Second), checkpoint.Written, checkpoint.Counted, checkpoint.Gap())
+					}
+				}
+			}
+		}()
+	} else {
+		close(checkpointsDone)
+	}
+
+	go func() {
+		select {
+		case <-time.After(time.Duration(config.SecondsCount+config.RampDownSeconds) * time.Second):
+		case <-stopExecution:
+			// the run was aborted
+			return
+		}
+		if !config.Quiet {
+			fmt.Printf("\n\nThe time: %v seconds elapsed! Stopping all writers\n\n", config.SecondsCount+config.RampDownSeconds)
+		}
+		stop()
+	}()
+	if writer, ok := config.Writer.(aborter); ok {
+		go func() {
+			select {
+			case <-writer.aborted():
+				if !config.Quiet {
+					fmt.Printf("\n\nA write failed! Aborting the run\n\n")
+				}
+				stop()
+			case <-stopExecution:
+			}
+		}()
+	}
+
+	wg.Wait()
+	if config.Model == "pipeline" {
+		// the pipeline is not paced, it ends once all calls are written
+		stop()
+	}
+	<-checkpointsDone
+	var readStats *ReadStats
+	if reads != nil {
+		stats := reads.finish()
+		readStats = &stats
+	}
+	if writer, ok := config.Writer.(flusher); ok {
+		// the asynchronous writer is not done until its buffer is written
+		writer.Flush()
+	}
+	var allocs *Allocs
+	if config.ReportAllocs {
+		measured := allocsSince(memStats)
+		allocs = &measured
+	}
+
+	result := Result{
+		Latencies:   latencies,
+		Samples:     MergeLatencies(latencies),
+		Elapsed:     time.Since(start),
+		Expected:    config.ThreadsCount * config.SecondsCount * config.LineProtocolsCount,
+		Attempted:   atomic.LoadInt64(&written),
+		Checkpoints: checkpoints,
+		Allocs:      allocs,
+		Reads:       readStats,
+	}
+	if config.Expected > 0 {
+		result.Expected = config.Expected
+	}
+	if allocs != nil {
+		allocs.Writes = len(result.Samples)
+	}
+	if config.RampDownSeconds > 0 {
+		var loadEnd time.Time
+		for _, end := range loadEnds {
+			if end.After(loadEnd) {
+				loadEnd = end
+			}
+		}
+		if !loadEnd.IsZero() {
+			result.Elapsed = loadEnd.Sub(start)
+		}
+	}
+	if writes != nil {
+		result.Timeline = writes.buckets()
+	}
+	if clocks != nil {
+		overhead := SyncOverhead{}
+		for _, clock := range clocks {
+			overhead.Selects += clock.selects
+			overhead.Pacing += clock.pacing
+		}
+		for _, latency := range result.Samples {
+			overhead.Writes += latency
+		}
+		if reporter, ok := config.Writer.(lockReporter); ok {
+			overhead.Locks = reporter.lockWait()
+		}
+		result.SyncOverhead = &overhead
+	}
+	if sampler != nil {
+		latency := sampler.finish()
+		result.SchedLatency = &latency
+	}
+	if goroutines != nil {
+		stats := goroutines.finish()
+		result.Goroutines = &stats
+	}
+	if len(result.Samples) > 0 {
+		for _, percentile := range config.Percentiles {
+			result.Percentiles = append(result.Percentiles, Percentile{percentile, LatencyPercentile(result.Samples, percentile)})
+		}
+	}
+	if multi, ok := config.Writer.(*WriterMulti); ok {
+		for i, url := range multi.Urls() {
+			result.Endpoints = append(result.Endpoints, EndpointResult{Url: url, Writes: multi.Writes(i)})
+		}
+	}
+
+	if counter, ok := config.Writer.(ConnectionCounter); ok {
+		// the connections are taken before the count queries that may dial more of them
+		if connections, counted := counter.Connections(); counted {
+			result.Connections = &connections
+		}
+	}
+
+	if !config.SkipCount {
+		if !config.Quiet {
+			fmt.Println()
+			fmt.Println()
+			fmt.Println("Querying InfluxDB ...")
+		}
+		if config.CountDelay > 0 {
+			if !config.Quiet {
+				fmt.Printf("Waiting %v for ingestion before counting ...\n", config.CountDelay)
+			}
+			time.Sleep(config.CountDelay)
+		}
+		countStart := time.Now()
+		total, polls, shardTimes, err := stableCount(config)
+		if err != nil {
+			return result, err
+		}
+		result.CountTime = time.Since(countStart)
+		result.CountPolls = polls
+		result.CountShardTimes = shardTimes
+		result.Counted = true
+		result.Total = total - config.Baseline
+		result.Baseline = config.Baseline
+		if result.Expected > 0 {
+			result.Rate = float64(result.Total) / float64(result.Expected) * 100
+		}
+		if result.Attempted > 0 {
+			result.AttemptedRate = float64(result.Total) / float64(result.Attempted) * 100
+		}
+	}
+
+	if reporter, ok := config.Writer.(StatsReporter); ok {
+		stats := reporter.WriteStats()
+		result.Stats = &stats
+	}
+	if reporter, ok := config.Writer.(batchSizeReporter); ok {
+		if sizes := reporter.batchSizes(); len(sizes) > 0 {
+			stats := newBatchSizeStats(sizes)
+			result.BatchSizes = &stats
+		}
+	}
+	if reporter, ok := config.Writer.(ErrorReporter); ok {
+		if stats := reporter.ErrorStats(); stats.Policy != "" {
+			result.Errors = &stats
+		}
+	}
+	if reporter, ok := config.Writer.(PendingReporter); ok {
+		if stats := reporter.PendingStats(); stats.MaxPending > 0 {
+			result.Pending = &stats
+		}
+	}
+	if reporter, ok := config.Writer.(RotationReporter); ok {
+		stats := reporter.RotationStats()
+		result.Rotation = &stats
+	}
+	if multi, ok := config.Writer.(*WriterMulti); ok {
+		for i, writer := range multi.writers {
+			if reporter, ok := writer.(ConnectionReporter); ok {
+				setup := reporter.ConnectionSetup()
+				setup.Url = multi.urls[i]
+				result.ConnectionSetups = append(result.ConnectionSetups, setup)
+			}
+		}
+	} else if reporter, ok := config.Writer.(ConnectionReporter); ok {
+		result.ConnectionSetups = append(result.ConnectionSetups, reporter.ConnectionSetup())
+	}
+	return result, nil
+}
+
+// loadThread is the state of one thread of doLoad
+type loadThread struct {
+	id int
+	// latencies receive the latencies of Write calls of the thread, loadEnd the time it finished the measured load
+	// before its ramp-down
+	latencies *[]time.Duration
+	loadEnd   *time.Time
+	// clock measures the synchronization of the thread, it is nil without Config.ReportSyncOverhead
+	clock *syncClock
+	// written and writes are shared by all threads, writes is nil without Config.TimelineBucket
+	written *int64
+	writes  *timeline
+}
+
+func doLoad(wg *sync.WaitGroup, stopExecution <-chan bool, config Config, thread loadThread) {
+	defer wg.Done()
+
+	iterations := make([]int, 0, config.PointsPerCall)
+	var arrivals *rand.Rand
+	// loadStart schedules the seconds of poisson arrivals, so the schedule doesn't drift by the time of the calls
+	loadStart := time.Now()
+	if config.Arrival == "poisson" {
+		arrivals = rand.New(rand.NewSource(loadStart.UnixNano() + int64(thread.id)))
+	}
+
+	for i := 1; i <= config.SecondsCount+config.RampDownSeconds; i++ {
+		selectStart := thread.clock.now()
+		select {
+		case <-stopExecution:
+			return
+		default:
+			thread.clock.selected(selectStart)
+
+			if thread.id == 1 && !config.Quiet {
+				fmt.Printf("\rwriting iterations: %v/%v", i, config.SecondsCount+config.RampDownSeconds)
+			}
+
+			start := i * config.LineProtocolsCount
+			end := start + config.LineProtocolsCount
+			name := config.MeasurementName
+			rampDown := i > config.SecondsCount
+			if rampDown {
+				if i == config.SecondsCount+1 {
+					*thread.loadEnd = time.Now()
+				}
+				// the count of points of the ramp-down seconds decreases linearly to zero
+				end = start + config.LineProtocolsCount*(config.SecondsCount+config.RampDownSeconds+1-i)/(config.RampDownSeconds+1)
+				name = config.MeasurementName + "_rampdown"
+			}
+			secondStart := loadStart.Add(time.Duration(i-1) * time.Second)
+			var offsets []time.Duration
+			if config.Arrival == "poisson" {
+				// arrivals of a Poisson process with a known count are uniformly distributed over the interval
+				offsets = make([]time.Duration, (end-start+config.PointsPerCall-1)/config.PointsPerCall)
+				for k := range offsets {
+					offsets[k] = time.Duration(arrivals.Int63n(int64(time.Second)))
+				}
+				sort.Slice(offsets, func(a, b int) bool { return offsets[a] < offsets[b] })
+			}
+			call := 0
+			for j := start; j < end; {
+				selectStart := thread.clock.now()
+				select {
+				case <-stopExecution:
+					return
+				default:
+					thread.clock.selected(selectStart)
+					if call < len(offsets) {
+						waitStart := thread.clock.now()
+						if !wait(stopExecution, secondStart.Add(offsets[call])) {
+							return
+						}
+						thread.clock.paced(waitStart)
+					}
+					call++
+					callEnd := j + config.PointsPerCall
+					if callEnd > end {
+						callEnd = end
+					}
+					if config.MeasurementSwitchEvery > 0 && !rampDown {
+						// one call doesn't cross the switch of the measurement
+						if next := (j/config.MeasurementSwitchEvery + 1) * config.MeasurementSwitchEvery; next < callEnd {
+							callEnd = next
+						}
+						name = config.MeasurementName
+						if (j/config.MeasurementSwitchEvery)%2 == 1 {
+							name = AlternateMeasurement(config.MeasurementName)
+						}
+					}
+					iterations = iterations[:0]
+					for k := j; k < callEnd; k++ {
+						iterations = append(iterations, k)
+					}
+					j = callEnd
+					writeStart := time.Now()
+					if config.ClientDelay > 0 {
+						time.Sleep(config.ClientDelay)
+					}
+					config.Writer.Write(thread.id, name, iterations)
+					if thread.writes != nil {
+						thread.writes.add(len(iterations))
+					}
+					if !rampDown {
+						*thread.latencies = append(*thread.latencies, time.Since(writeStart))
+						atomic.AddInt64(thread.written, int64(len(iterations)))
+					}
+				}
+			}
+			waitStart := thread.clock.now()
+			if config.Arrival == "poisson" {
+				if !wait(stopExecution, secondStart.Add(time.Second)) {
+					return
+				}
+			} else {
+				time.Sleep(time.Duration(1) * time.Second)
+			}
+			thread.clock.paced(waitStart)
+		}
+	}
+}
+
+// wait waits until the time, it returns false when the execution was stopped meanwhile
+func wait(stopExecution <-chan bool, until time.Time) bool {
+	delay := time.Until(until)
+	if delay <= 0 {
+		return true
+	}
+	select {
+	case <-stopExecution:
+		return false
+	case <-time.After(delay):
+		return true
+	}
+}
+
+// CountPollInterval is the period of count queries of Config.CountStabilizeTimeout
+const CountPollInterval = time.Second
+
+// stableCount counts the points until two counts in a row are the same or Config.CountStabilizeTimeout passes,
+// it returns the last count, the count of queries and the shard times of the last count
+func stableCount(config Config) (int, int, []time.Duration, error) {
+	total, shardTimes, err := count(config)
+	if err != nil || config.CountStabilizeTimeout <= 0 {
+		return total, 1, shardTimes, err
+	}
+	deadline := time.Now().Add(config.CountStabilizeTimeout)
+	polls := 1
+	for time.Now().Add(CountPollInterval).Before(deadline) {
+		time.Sleep(CountPollInterval)
+		next, nextShardTimes, err := count(config)
+		polls++
+		if err != nil {
+			return total, polls, shardTimes, err
+		}
+		shardTimes = nextShardTimes
+		if next == total {
+			break
+		}
+		if !config.Quiet {
+			fmt.Printf("-> count %v changed to %v, counting again ...\n", total, next)
+		}
+		total = next
+	}
+	return total, polls, shardTimes, nil
+}
+
+// CountExisting counts the points already in the measurements of the load of config before it starts,
+// so that stale points of a reused measurement are detected or subtracted by Config.Baseline
+func CountExisting(config Config) (int, error) {
+	total, _, err := count(config)
+	return total, err
+}
+
+// AlternateMeasurement returns the second measurement of Config.MeasurementSwitchEvery
+func AlternateMeasurement(measurementName string) string {
+	return measurementName + "_alt"
+}
+
+// count counts the points of the measurement of the load, or of both measurements it switches between,
+// the shard times are set only by Config.CountTimeShards
+func count(config Config) (int, []time.Duration, error) {
+	total, shardTimes, err := countMeasurement(config, config.MeasurementName)
+	if err != nil || config.MeasurementSwitchEvery <= 0 {
+		return total, shardTimes, err
+	}
+	alternate, alternateShardTimes, err := countMeasurement(config, AlternateMeasurement(config.MeasurementName))
+	return total + alternate, append(shardTimes, alternateShardTimes...), err
+}
+
+// readCheckpoint counts the points of the measurements of the load like count, but the buffered points
+// of the writer are left intact, so that the checkpoints don't change the batches of the load
+func readCheckpoint(config Config) (int, error) {
+	total, err := readCount(config.Writer, config.MeasurementName)
+	if err != nil || config.MeasurementSwitchEvery <= 0 {
+		return total, err
+	}
+	alternate, err := readCount(config.Writer, AlternateMeasurement(config.MeasurementName))
+	return total + alternate, err
+}
+
+func countMeasurement(config Config, measurementName string) (int, []time.Duration, error) {
+	if config.CountTimeShards > 1 {
+		return shardedCount(config, measurementName)
+	}
+	total, err := config.Writer.Count(measurementName)
+	return total, nil, err
+}
+
+// MergeLatencies merges latencies recorded by all threads into sorted samples
+func MergeLatencies(latencies [][]time.Duration) []time.Duration {
+	var samples []time.Duration
+	for _, threadLatencies := range latencies {
+		samples = append(samples, threadLatencies...)
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	return samples
+}
+
+// WriteLatencies writes latencies of all threads in nanoseconds, one per line, in the order of the threads and their writes
+func WriteLatencies(w io.Writer, latencies [][]time.Duration) error {
+	buffered := bufio.NewWriter(w)
+	for _, threadLatencies := range latencies {
+		for _, latency := range threadLatencies {
+			buffered.WriteString(strconv.FormatInt(int64(latency), 10))
+			if err := buffered.WriteByte('\n'); err != nil {
+				return err
+			}
+		}
+	}
+	return buffered.Flush()
+}
+
+// LatencyPercentile returns nearest-rank percentile of sorted samples
+func LatencyPercentile(sorted []time.Duration, percentile float64) time.Duration {
+	rank := int(math.Ceil(percentile / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,60 @@
+package bench
+
+import "time"
+
+// SchedLatencyInterval is the period of the timer sampling scheduling latency
+const SchedLatencyInterval = 10 * time.Millisecond
+
+// SchedLatency is the delay of goroutine wake-ups after the intended tick of a timer
+type SchedLatency struct {
+	Samples int
+	Max     time.Duration
+	Avg     time.Duration
+}
+
+// schedSampler measures how late a goroutine wakes up from a timer of a known interval
+type schedSampler struct {
+	stop    chan bool
+	done    chan bool
+	latency SchedLatency
+}
+
+func startSchedSampler(interval time.Duration) *schedSampler {
+	s := &schedSampler{stop: make(chan bool), done: make(chan bool)}
+	go func() {
+		defer close(s.done)
+		var total time.Duration
+		timer := time.NewTimer(interval)
+		defer timer.Stop()
+		intended := time.Now().Add(interval)
+		for {
+			select {
+			case <-s.stop:
+				if s.latency.Samples > 0 {
+					s.latency.Avg = total / time.Duration(s.latency.Samples)
+				}
+				return
+			case <-timer.C:
+				delay := time.Since(intended)
+				if delay < 0 {
+					delay = 0
+				}
+				s.latency.Samples++
+				total += delay
+				if delay > s.latency.Max {
+					s.latency.Max = delay
+				}
+				intended = time.Now().Add(interval)
+				timer.Reset(interval)
+			}
+		}
+	}()
+	return s
+}
+
+// finish stops the sampling and returns the measured latency
+func (s *schedSampler) finish() SchedLatency {
+	close(s.stop)
+	<-s.done
+	return s.latency
+}
@@ -0,0 +1,199 @@
+package bench
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// SelfTestResult is the outcome of one writer of RunSelfTest
+type SelfTestResult struct {
+	WriterType string
+	Expected   int
+	Total      int
+	// Err is the error of the run or of the count
+	Err error
+}
+
+// Ok tells whether the fake server received all points written by the writer
+func (r SelfTestResult) Ok() bool {
+	return r.Err == nil && r.Total == r.Expected
+}
+
+// RunSelfTest runs a short load of every writer of AllWriterTypes against an in-process fake InfluxDB and compares
+// the counted points with the expected ones. The UnixSocket and Cloud settings of writerConfig are ignored.
+func RunSelfTest(writerConfig WriterConfig, config Config) []SelfTestResult {
+	server := httptest.NewServer(newFakeInflux())
+	defer server.Close()
+	writerConfig.UnixSocket = ""
+	writerConfig.Cloud = false
+	results := make([]SelfTestResult, 0, len(AllWriterTypes))
+	for _, writerType := range AllWriterTypes {
+		if !config.Quiet {
+			fmt.Println("Testing", writerType, "...")
+		}
+		writer := NewWriter(writerType, server.URL, writerConfig)
+		load := config
+		load.Writer = writer
+		load.SkipCount = false
+		load.MeasurementName = config.MeasurementName + "_" + strings.ToLower(writerType)
+		result, err := Run(load)
+		if closeErr := writer.Close(); err == nil {
+			err = closeErr
+		}
+		results = append(results, SelfTestResult{WriterType: writerType, Expected: result.Expected, Total: result.Total, Err: err})
+		if !config.Quiet {
+			fmt.Println()
+		}
+	}
+	return results
+}
+
+// fakeInflux emulates the write, query and health endpoints of InfluxDB 1.x and 2.x well enough to count
+// the points of the benchmark, points of the same series and timestamp are stored once like InfluxDB does
+type fakeInflux struct {
+	lock sync.Mutex
+	// points are the field sets of points by measurement and by the series key with the timestamp
+	points map[string]map[string]string
+}
+
+func newFakeInflux() http.Handler {
+	fake := &fakeInflux{points: make(map[string]map[string]string)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/write", fake.write)
+	mux.HandleFunc("/api/v2/write", fake.write)
+	mux.HandleFunc("/query", fake.queryInfluxQL)
+	mux.HandleFunc("/api/v2/query", fake.queryFlux)
+	mux.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"name":"influxdb","status":"pass"}`))
+	})
+	return mux
+}
+
+func (f *fakeInflux) write(w http.ResponseWriter, r *http.Request) {
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gzipped, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		body = gzipped
+	}
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		first, last := strings.Index(line, " "), strings.LastIndex(line, " ")
+		if first < 0 || first == last {
+			http.Error(w, fmt.Sprintf(`{"error":"unable to parse '%s': missing fields or timestamp"}`, line), http.StatusBadRequest)
+			return
+		}
+		series, fields, timestamp := line[:first], line[first+1:last], line[last+1:]
+		measurement := strings.SplitN(series, ",", 2)[0]
+		if f.points[measurement] == nil {
+			f.points[measurement] = make(map[string]string)
+		}
+		key := series + " " + timestamp
+		if stored, ok := f.points[measurement][key]; ok {
+			fields = stored + "," + fields
+		}
+		f.points[measurement][key] = fields
+	}
+	if err := scanner.Err(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// count returns the count of points of the measurement with the field, and with the run tag when not empty
+func (f *fakeInflux) count(measurement string, field string, run string) int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	total := 0
+	for key, fields := range f.points[measurement] {
+		if run != "" && !strings.Contains(strings.SplitN(key, " ", 2)[0]+",", ",run="+run+",") {
+			continue
+		}
+		if strings.Contains(","+fields, ","+field+"=") {
+			total++
+		}
+	}
+	return total
+}
+
+var (
+	fakeInfluxQLCount = regexp.MustCompile(`SELECT count\("([^"]+)"\) FROM "?([^" ]+)"?`)
+	fakeInfluxQLRun   = regexp.MustCompile(`"run" = '([^']*)'`)
+	fakeFluxMeasure   = regexp.MustCompile(`r\._measurement == "([^"]*)"`)
+	fakeFluxField     = regexp.MustCompile(`r\._field == "([^"]*)"`)
+	fakeFluxColumn    = regexp.MustCompile(`count\(column: "([^"]*)"\)`)
+	fakeFluxRun       = regexp.MustCompile(`r\.run == "([^"]*)"`)
+)
+
+// queryInfluxQL answers the count query of countInfluxQL
+func (f *fakeInflux) queryInfluxQL(w http.ResponseWriter, r *http.Request) {
+	query := r.FormValue("q")
+	match := fakeInfluxQLCount.FindStringSubmatch(query)
+	if match == nil {
+		http.Error(w, fmt.Sprintf(`{"error":"unsupported query: %s"}`, query), http.StatusBadRequest)
+		return
+	}
+	run := ""
+	if runMatch := fakeInfluxQLRun.FindStringSubmatch(query); runMatch != nil {
+		run = runMatch[1]
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"results": []interface{}{map[string]interface{}{
+			"statement_id": 0,
+			"series": []interface{}{map[string]interface{}{
+				"name":    match[2],
+				"columns": []string{"time", "count"},
+				"values":  [][]interface{}{{0, f.count(match[2], match[1], run)}},
+			}},
+		}},
+	})
+}
+
+// queryFlux answers the count query of the V2 writer in annotated CSV
+func (f *fakeInflux) queryFlux(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Query string `json:"query"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	measurement := fakeFluxMeasure.FindStringSubmatch(request.Query)
+	field := fakeFluxField.FindStringSubmatch(request.Query)
+	column := fakeFluxColumn.FindStringSubmatch(request.Query)
+	if measurement == nil || field == nil || column == nil {
+		http.Error(w, `{"code":"invalid","message":"unsupported query"}`, http.StatusBadRequest)
+		return
+	}
+	run := ""
+	if runMatch := fakeFluxRun.FindStringSubmatch(request.Query); runMatch != nil {
+		run = runMatch[1]
+	}
+	w.Header().Set("Content-Type", "text/csv")
+	_, _ = fmt.Fprintf(w, "#datatype,string,long,long\n#group,false,false,false\n#default,_result,,\n,result,table,%s\n,,0,%d\n\n",
+		column[1], f.count(measurement[1], field[1], run))
+}
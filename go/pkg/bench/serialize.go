@@ -0,0 +1,85 @@
+package bench
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/influxdata/influxdb-client-go"
+	client "github.com/influxdata/influxdb1-client/v2"
+	lp "github.com/influxdata/line-protocol"
+	"sync"
+	"time"
+)
+
+// Serializer builds a point and encodes it into line protocol, it returns the size of the encoded point
+type Serializer func(id int, measurementName string, iteration int) (int, error)
+
+// NewSerializer creates a Serializer that uses point construction and encoding of the given client version
+func NewSerializer(version string, points PointOptions) (Serializer, error) {
+	switch version {
+	case "CLIENT_GO_V1":
+		return func(id int, measurementName string, iteration int) (int, error) {
+			tags := points.tags(id, iteration)
+			pt, err := client.NewPoint(measurementName, tags, points.fields(iteration), time.Unix(0, points.timestamp(iteration)))
+			if err != nil {
+				return 0, err
+			}
+			return len(pt.String()) + 1, nil
+		}, nil
+	case "CLIENT_GO_V2":
+		return func(id int, measurementName string, iteration int) (int, error) {
+			point := influxdb2.NewPoint(
+				measurementName,
+				points.tags(id, iteration),
+				points.fields(iteration),
+				time.Unix(0, points.timestamp(iteration)))
+			var buffer bytes.Buffer
+			encoder := lp.NewEncoder(&buffer)
+			encoder.SetFieldTypeSupport(lp.UintSupport)
+			encoder.FailOnFieldErr(true)
+			if _, err := encoder.Encode(point); err != nil {
+				return 0, err
+			}
+			return buffer.Len(), nil
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported serialize version: %s", version)
+	}
+}
+
+// RunSerialize serializes points in a tight loop of all threads for secondsCount,
+// it returns the count of serialized points and their total size in bytes
+func RunSerialize(threadsCount int, secondsCount int, measurementName string, serialize Serializer) (int, int) {
+	stopExecution := make(chan bool)
+	counts := make([]int, threadsCount)
+	sizes := make([]int, threadsCount)
+	var wg sync.WaitGroup
+	wg.Add(threadsCount)
+	for i := 1; i <= threadsCount; i++ {
+		go func(id int) {
+			defer wg.Done()
+			for iteration := 0; ; iteration++ {
+				select {
+				case <-stopExecution:
+					return
+				default:
+					size, err := serialize(id, measurementName, iteration)
+					if err != nil {
+						panic(err)
+					}
+					counts[id-1]++
+					sizes[id-1] += size
+				}
+			}
+		}(i)
+	}
+	time.Sleep(time.Duration(secondsCount) * time.Second)
+	close(stopExecution)
+	wg.Wait()
+
+	count, size := 0, 0
+	for i := range counts {
+		count += counts[i]
+		size += sizes[i]
+	}
+	return count, size
+}
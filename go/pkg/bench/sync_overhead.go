@@ -0,0 +1,94 @@
+package bench
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SyncOverhead is the aggregate time all threads of the load spent on the synchronization of the harness
+type SyncOverhead struct {
+	// Selects is the time spent in the selects of the stop of the execution between Write calls
+	Selects time.Duration
+	// Pacing is the time spent waiting for the next second, or the next arrival, while watching the stop
+	Pacing time.Duration
+	// Locks is the time spent waiting for shared locks of writers that measure it by WriterConfig.ReportLockWait
+	Locks time.Duration
+	// Writes is the time spent in Write calls of the measured load, it includes Locks
+	Writes time.Duration
+}
+
+// syncClock measures the synchronization of one thread of the load, a nil clock measures nothing
+type syncClock struct {
+	selects time.Duration
+	pacing  time.Duration
+}
+
+// now returns the current time, or the zero time without measuring
+func (c *syncClock) now() time.Time {
+	if c == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// selected adds the time of the select started at start
+func (c *syncClock) selected(start time.Time) {
+	if c != nil {
+		c.selects += time.Since(start)
+	}
+}
+
+// paced adds the time of the pacing started at start
+func (c *syncClock) paced(start time.Time) {
+	if c != nil {
+		c.pacing += time.Since(start)
+	}
+}
+
+// lockTimer measures the time spent waiting for locks, a nil timer just locks
+type lockTimer struct {
+	// waited is in nanoseconds, it is updated atomically
+	waited int64
+}
+
+func newLockTimer(config WriterConfig) *lockTimer {
+	if !config.ReportLockWait {
+		return nil
+	}
+	return &lockTimer{}
+}
+
+// lock locks the lock and measures the wait for it
+func (t *lockTimer) lock(lock sync.Locker) {
+	if t == nil {
+		lock.Lock()
+		return
+	}
+	start := time.Now()
+	lock.Lock()
+	atomic.AddInt64(&t.waited, int64(time.Since(start)))
+}
+
+func (t *lockTimer) total() time.Duration {
+	if t == nil {
+		return 0
+	}
+	return time.Duration(atomic.LoadInt64(&t.waited))
+}
+
+// lockReporter is implemented by writers that measure the wait for their shared locks
+type lockReporter interface {
+	lockWait() time.Duration
+}
+
+// sumLockWait sums the wait for locks of the writers that measure it
+func sumLockWait(writers []Writer) time.Duration {
+	var total time.Duration
+	for _, writer := range writers {
+		if reporter, ok := writer.(lockReporter); ok {
+			total += reporter.lockWait()
+		}
+	}
+	return total
+}
@@ -0,0 +1,58 @@
+package bench
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// TimelineBucket counts Write calls, and their points, that finished within a bucket of the load
+type TimelineBucket struct {
+	// Start is the start of the bucket since the start of the load
+	Start  time.Duration
+	Writes int64
+	Points int64
+}
+
+// PointsPerSecond returns the throughput of the bucket of the given length
+func (b TimelineBucket) PointsPerSecond(length time.Duration) float64 {
+	return float64(b.Points) / length.Seconds()
+}
+
+// timeline counts finished Write calls by buckets of the load, it is updated atomically by all threads
+type timeline struct {
+	start  time.Time
+	length time.Duration
+	writes []int64
+	points []int64
+}
+
+// newTimeline creates the buckets of the given length covering the duration, later writes fall into the last bucket
+func newTimeline(start time.Time, length time.Duration, duration time.Duration) *timeline {
+	count := int((duration + length - 1) / length)
+	if count < 1 {
+		count = 1
+	}
+	return &timeline{start: start, length: length, writes: make([]int64, count), points: make([]int64, count)}
+}
+
+// add counts the Write call of the given points that finished now
+func (t *timeline) add(points int) {
+	index := int(time.Since(t.start) / t.length)
+	if index >= len(t.writes) {
+		index = len(t.writes) - 1
+	}
+	atomic.AddInt64(&t.writes[index], 1)
+	atomic.AddInt64(&t.points[index], int64(points))
+}
+
+func (t *timeline) buckets() []TimelineBucket {
+	buckets := make([]TimelineBucket, len(t.writes))
+	for i := range buckets {
+		buckets[i] = TimelineBucket{
+			Start:  time.Duration(i) * t.length,
+			Writes: atomic.LoadInt64(&t.writes[i]),
+			Points: atomic.LoadInt64(&t.points[i]),
+		}
+	}
+	return buckets
+}
@@ -0,0 +1,31 @@
+package bench
+
+import (
+	"context"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+)
+
+// tracerName is the instrumentation name of the spans of write requests
+const tracerName = "go-bechmark/pkg/bench"
+
+// StartTracing exports spans of the raw writers created with WriterConfig.Tracing to the OTLP/HTTP traces endpoint,
+// like http://localhost:4318/v1/traces, and propagates the trace context into write requests.
+// The returned function flushes the remaining spans and stops the export.
+func StartTracing(endpoint string) (func(ctx context.Context) error, error) {
+	exporter, err := otlptracehttp.New(context.Background(), otlptracehttp.WithEndpointURL(endpoint))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName("go-bechmark"))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return provider.Shutdown, nil
+}
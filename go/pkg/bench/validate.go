@@ -0,0 +1,304 @@
+package bench
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RunOptions select how the writers are run by the command line, they are validated together with the configurations
+// of the run and of the writers
+type RunOptions struct {
+	// Type is a writer type or one of ModeTypes, ClientType is the type of the writers it runs
+	Type       string
+	ClientType string
+	// Repeat is the count of repetitions of the run
+	Repeat int
+	// Output is the format of the results, "text", "markdown" or "json"
+	Output string
+	// Urls are the endpoints the load is distributed across, empty means the default endpoint of ClientType
+	Urls            []string
+	ClientPerWorker bool
+	// RequireEmpty and AppendMode count the points of the measurement before the run, see CountExisting
+	RequireEmpty bool
+	AppendMode   bool
+	// VerifyOrder verifies the ordered timestamps of VerifyOrderSeries series after the run, see VerifyOrder
+	VerifyOrder       bool
+	VerifyOrderSeries int
+	// TraceBatches and KeepDeadLetters tell that WriterConfig.BatchTrace and WriterConfig.DeadLetters will be set
+	TraceBatches    bool
+	KeepDeadLetters bool
+	// PrintCountQuery prints the count query of ClientType instead of the run
+	PrintCountQuery bool
+	// Sweep is the search of the BATCH_SWEEP type
+	Sweep BatchSweepConfig
+}
+
+// singleRun tells whether the options run a single writer once
+func (o RunOptions) singleRun() bool {
+	return !IsModeType(o.Type) && o.Repeat <= 1
+}
+
+// Validate checks the options and their combinations with the configurations of the run and of the writers,
+// the configurations are validated too
+func (o RunOptions) Validate(run Config, writer WriterConfig) error {
+	if err := run.Validate(); err != nil {
+		return err
+	}
+	if err := writer.Validate(); err != nil {
+		return err
+	}
+	modeTypes := strings.Join(ModeTypes, ", ")
+	switch o.Output {
+	case "text", "markdown", "json":
+	default:
+		return fmt.Errorf("unsupported output: %v", o.Output)
+	}
+	if o.Output == "json" && !o.singleRun() {
+		return fmt.Errorf("json output is supported only by single runs of a writer, not by repeat and the %v types", modeTypes)
+	}
+	if o.Repeat < 1 {
+		return fmt.Errorf("repeat has to be positive: %v", o.Repeat)
+	}
+	if o.Repeat > 1 && IsModeType(o.Type) {
+		return fmt.Errorf("repeat is not supported by the %v type", o.Type)
+	}
+	if writer.BatchSizeMax > 0 && o.Type == "BATCH_SWEEP" {
+		return fmt.Errorf("batchSizeMin and batchSizeMax can't be combined with BATCH_SWEEP")
+	}
+	if o.Type == "AUTOTUNE" && !strings.HasPrefix(o.ClientType, "HTTP_GO_") {
+		return fmt.Errorf("AUTOTUNE needs the error rate of HTTP_GO_V1 or HTTP_GO_V2, the %v writer doesn't report failed writes", o.ClientType)
+	}
+	if o.Type == "BATCH_SWEEP" {
+		if err := o.Sweep.Validate(); err != nil {
+			return err
+		}
+	}
+	if o.ClientPerWorker && (len(o.Urls) > 0 || o.Type == "AUTOTUNE" || o.Type == "HTTP_SINK") {
+		return fmt.Errorf("clientPerWorker can't be combined with urls, AUTOTUNE and HTTP_SINK")
+	}
+	if o.RequireEmpty && o.AppendMode {
+		return fmt.Errorf("requireEmpty and appendMode can't be combined")
+	}
+	if (o.RequireEmpty || o.AppendMode) && run.SkipCount {
+		return fmt.Errorf("requireEmpty and appendMode can't be combined with skipCount")
+	}
+	if (o.RequireEmpty || o.AppendMode) && !o.singleRun() {
+		return fmt.Errorf("requireEmpty and appendMode are supported only by single runs of a writer, not by repeat and the %v types", modeTypes)
+	}
+	if o.VerifyOrder {
+		if writer.Points.OrderedClock == nil {
+			return fmt.Errorf("verifyOrder requires orderedTimestamps")
+		}
+		if run.RampDownSeconds > 0 || run.MeasurementSwitchEvery > 0 {
+			// points of the other measurements take ticks of the clock too
+			return fmt.Errorf("verifyOrder can't be combined with rampDownSeconds or measurementSwitchEvery")
+		}
+		if o.VerifyOrderSeries < 1 {
+			return fmt.Errorf("verifyOrderSeries has to be positive: %v", o.VerifyOrderSeries)
+		}
+	}
+	if writer.Cloud && (len(o.Urls) == 0 || !strings.HasSuffix(o.ClientType, "_V2")) {
+		return fmt.Errorf("cloud requires urls of the Cloud region and a V2 writer")
+	}
+	if o.TraceBatches && !o.singleRun() {
+		return fmt.Errorf("batchTraceOut is supported only by single runs of a writer, not by repeat and the %v types", modeTypes)
+	}
+	if o.KeepDeadLetters && ((IsModeType(o.Type) && o.Type != "COMPARE_V2") || o.Repeat > 1) {
+		return fmt.Errorf("deadLetterFile is supported only by single runs of a writer and COMPARE_V2, not by repeat and the other %v types", modeTypes)
+	}
+	if o.PrintCountQuery && IsModeType(o.ClientType) {
+		// the client type replaces the tuned and replaying types
+		return fmt.Errorf("printCountQuery is supported only by writers and the types running one writer, not by the %v types", modeTypes)
+	}
+	return nil
+}
+
+// Warnings return the settings of the run and of the writers that ClientType doesn't support, they are ignored by it
+func (o RunOptions) Warnings(run Config, writer WriterConfig) []string {
+	raw := strings.HasPrefix(o.ClientType, "HTTP_")
+	record := strings.HasSuffix(o.ClientType, "_V2") && writer.V2WriteMode == "record"
+	var warnings []string
+	if writer.CountStart != "" && (!strings.HasSuffix(o.ClientType, "_V2") || writer.CountLang != "flux") {
+		warnings = append(warnings, "countRange is supported only by the flux count query of V2 writers")
+	}
+	if len(writer.ExtraHeaders) > 0 && !raw {
+		warnings = append(warnings, "extra headers are supported only by HTTP_GO_V1, HTTP_GO_V2 and HTTP_SINK writers")
+	}
+	if len(writer.DefaultTags) > 0 && ((o.ClientType != "CLIENT_GO_V2" && o.Type != "COMPARE_V2") || writer.V2WriteMode != "point") {
+		warnings = append(warnings, "defaultTags are supported only by CLIENT_GO_V2 and COMPARE_V2 writers in the point v2WriteMode, the points are written without them")
+	}
+	if run.ConcurrentReads > 0 && o.ClientType == "HTTP_SINK" {
+		warnings = append(warnings, "concurrentReads is supported only by writers counting by a query of InfluxDB, the sink only counts its points")
+	}
+	if writer.HostHeader != "" && !raw {
+		warnings = append(warnings, "hostHeader is supported only by HTTP_GO_V1, HTTP_GO_V2 and HTTP_SINK writers, requests are sent with the host of the URL")
+	}
+	if writer.Points.FloatFormat.verb != 0 && !raw && o.ClientType != "UDP_V1" && !record {
+		warnings = append(warnings, "floatFormat is supported only by HTTP_GO_V1, HTTP_GO_V2, HTTP_SINK and UDP_V1 writers and the record v2WriteMode, the client formats the floats")
+	}
+	if writer.BatchSizeMax > 0 && !raw && o.ClientType != "UDP_V1" {
+		warnings = append(warnings, "batchSizeMin and batchSizeMax are supported only by HTTP_GO_V1, HTTP_GO_V2, HTTP_SINK and UDP_V1 writers, the batches have batchSize")
+	}
+	if writer.ReusePoints && !raw && !record {
+		warnings = append(warnings, "reusePoints is supported only by HTTP_GO_V1, HTTP_GO_V2 and HTTP_SINK writers and the record v2WriteMode, the points are generated for every write")
+	}
+	if writer.Retries != nil && !raw {
+		warnings = append(warnings, "retryBudget is supported only by HTTP_GO_V1, HTTP_GO_V2 and HTTP_SINK writers")
+	}
+	if o.KeepDeadLetters && !raw && o.Type != "COMPARE_V2" {
+		warnings = append(warnings, "deadLetterFile is supported only by HTTP_GO_V1, HTTP_GO_V2, HTTP_SINK and the sync writer of COMPARE_V2, the file stays empty")
+	}
+	if writer.Tracing && !raw && o.Type != "ALL" {
+		warnings = append(warnings, "otelEndpoint is supported only by HTTP_GO_V1, HTTP_GO_V2 and HTTP_SINK writers")
+	}
+	return warnings
+}
+
+// Validate checks the settings of the run, the Writer is not checked
+func (c Config) Validate() error {
+	if c.PointsPerCall < 1 {
+		return fmt.Errorf("pointsPerCall has to be positive: %v", c.PointsPerCall)
+	}
+	switch c.Arrival {
+	case "", "uniform", "poisson":
+	default:
+		return fmt.Errorf("unsupported arrival: %v", c.Arrival)
+	}
+	switch c.Model {
+	case "", "burst", "pipeline":
+	default:
+		return fmt.Errorf("unsupported model: %v", c.Model)
+	}
+	if c.Model == "pipeline" && ((c.Arrival != "" && c.Arrival != "uniform") || c.RampDownSeconds > 0) {
+		return fmt.Errorf("the pipeline model can't be combined with arrival and rampDownSeconds")
+	}
+	switch {
+	case c.TimelineBucket < 0:
+		return fmt.Errorf("the timeline bucket can't be negative: %v", c.TimelineBucket)
+	case c.ConcurrentReads < 0:
+		return fmt.Errorf("concurrentReads can't be negative: %v", c.ConcurrentReads)
+	case c.CheckpointInterval < 0:
+		return fmt.Errorf("the checkpoint interval can't be negative: %v", c.CheckpointInterval)
+	case c.RampDownSeconds < 0:
+		return fmt.Errorf("rampDownSeconds can't be negative: %v", c.RampDownSeconds)
+	case c.MeasurementSwitchEvery < 0:
+		return fmt.Errorf("measurementSwitchEvery can't be negative: %v", c.MeasurementSwitchEvery)
+	case c.ClientDelay < 0:
+		return fmt.Errorf("the client delay can't be negative: %v", c.ClientDelay)
+	case c.CountTimeShards < 0:
+		return fmt.Errorf("countTimeShards can't be negative: %v", c.CountTimeShards)
+	case c.CountDelay < 0 || c.CountStabilizeTimeout < 0:
+		return fmt.Errorf("the count delay and stabilize timeout can't be negative: %v, %v", c.CountDelay, c.CountStabilizeTimeout)
+	}
+	return nil
+}
+
+// Validate checks the settings of the writers and the options of their points
+func (c WriterConfig) Validate() error {
+	if c.BatchSizeMax > 0 {
+		min := c.BatchSizeMin
+		if min == 0 {
+			min = c.BatchSize
+		}
+		if min > c.BatchSizeMax {
+			return fmt.Errorf("batchSizeMax can't be below batchSizeMin: %v, %v", c.BatchSizeMax, min)
+		}
+	} else if c.BatchSizeMin > 0 {
+		return fmt.Errorf("batchSizeMin needs batchSizeMax")
+	}
+	switch c.OnError {
+	case "", "requeue", "drop", "abort":
+	default:
+		return fmt.Errorf("unsupported onError: %v", c.OnError)
+	}
+	if c.MaxPendingPoints < 0 {
+		return fmt.Errorf("maxPendingPoints can't be negative: %v", c.MaxPendingPoints)
+	}
+	switch c.PendingPolicy {
+	case "", "block", "drop":
+	default:
+		return fmt.Errorf("unsupported pendingPolicy: %v", c.PendingPolicy)
+	}
+	switch c.RawClient {
+	case "", "nethttp", "fasthttp":
+	default:
+		return fmt.Errorf("unsupported rawClient: %v", c.RawClient)
+	}
+	switch c.V2WriteMode {
+	case "", "point", "record":
+	default:
+		return fmt.Errorf("unsupported v2WriteMode: %v", c.V2WriteMode)
+	}
+	switch c.WriteConsistency {
+	case "", "one", "quorum", "all", "any":
+	default:
+		return fmt.Errorf("unsupported writeConsistency: %v", c.WriteConsistency)
+	}
+	switch c.CountLang {
+	case "", "flux", "influxql":
+	default:
+		return fmt.Errorf("unsupported countLang: %v", c.CountLang)
+	}
+	switch {
+	case c.WriteTimeout < 0:
+		return fmt.Errorf("writeTimeout can't be negative: %v", c.WriteTimeout)
+	case c.UDPPayloadBytes < 0:
+		return fmt.Errorf("udpPayloadBytes can't be negative: %v", c.UDPPayloadBytes)
+	case c.TokenRotateInterval < 0:
+		return fmt.Errorf("the token rotate interval can't be negative: %v", c.TokenRotateInterval)
+	case c.IdleConnTimeout < 0:
+		return fmt.Errorf("the idle connection timeout can't be negative: %v", c.IdleConnTimeout)
+	case c.Retries != nil && c.Retries.budget < 0:
+		return fmt.Errorf("retryBudget can't be negative: %v", c.Retries.budget)
+	}
+	return c.Points.Validate()
+}
+
+// Validate checks the options of the points and the combinations of their fields
+func (o PointOptions) Validate() error {
+	if o.TimestampScale < 1 {
+		return fmt.Errorf("timestampScale has to be positive: %v", o.TimestampScale)
+	}
+	if o.DuplicateRate < 0 || o.DuplicateRate >= 1 {
+		return fmt.Errorf("duplicateRate has to be in [0, 1): %v", o.DuplicateRate)
+	}
+	if o.DuplicateRate > 0 && o.OrderedClock != nil {
+		return fmt.Errorf("duplicateRate can't be combined with orderedTimestamps")
+	}
+	switch o.Precision {
+	case 0, time.Nanosecond, time.Microsecond, time.Millisecond, time.Second:
+	default:
+		return fmt.Errorf("unsupported precision: %v", o.Precision)
+	}
+	if o.precision() != time.Nanosecond && o.OrderedClock != nil {
+		return fmt.Errorf("precision can't be combined with orderedTimestamps")
+	}
+	if o.FieldKeyChurn < 0 {
+		return fmt.Errorf("fieldKeyChurn can't be negative: %v", o.FieldKeyChurn)
+	}
+	if o.IntFields && len(o.Fields) > 0 {
+		return fmt.Errorf("fieldSpec can't be combined with intFields")
+	}
+	floatFields := 0
+	for _, field := range o.Fields {
+		if (o.UintFields && field.Key == UintFieldKey) || (o.BoolFields && field.Key == BoolFieldKey) {
+			return fmt.Errorf("the field %v of fieldSpec is added by uintFields or boolFields", field.Key)
+		}
+		if field.Type == "float" {
+			floatFields++
+		}
+	}
+	if o.FloatFormat.verb != 0 && floatFields == 0 {
+		return fmt.Errorf("floatFormat needs float fields of fieldSpec")
+	}
+	return nil
+}
+
+// Validate checks the range of batch sizes and the factor of the sweep
+func (c BatchSweepConfig) Validate() error {
+	if c.MinBatchSize < 1 || c.Factor < 2 || c.MaxBatchSize < c.MinBatchSize {
+		return fmt.Errorf("BATCH_SWEEP needs positive sweepMinBatchSize, sweepFactor above 1 and sweepMaxBatchSize not below sweepMinBatchSize: %v, %v, %v", c.MinBatchSize, c.Factor, c.MaxBatchSize)
+	}
+	return nil
+}
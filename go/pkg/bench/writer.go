@@ -0,0 +1,426 @@
+// Package bench implements writers of the benchmark and the load driving them,
+// so the benchmark can be embedded into other Go programs and test harnesses.
+package bench
+
+import (
+	"fmt"
+	"github.com/influxdata/influxdb-client-go"
+	client "github.com/influxdata/influxdb1-client/v2"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type Writer interface {
+	// Write writes points of the iterations for the sensor id, the iterations slice is not retained
+	Write(id int, measurementName string, iterations []int)
+	Count(measurementName string) (int, error)
+	Close() error
+}
+
+// WriteStats counts outcomes of write requests, its fields are updated atomically
+type WriteStats struct {
+	// Batches counts all sent batches
+	Batches int64
+	// FailedBatches counts batches rejected by a hard error
+	FailedBatches int64
+	// PartialWrites counts batches accepted by the server with some of their points dropped
+	PartialWrites int64
+	// DroppedPoints counts points dropped by partial writes, as reported by the server
+	DroppedPoints int64
+	// Connections counts dialed TCP connections
+	Connections int64
+	// SentBytes counts bytes of line protocol of all write requests including retries
+	SentBytes int64
+	// WrittenBytes counts bytes of line protocol of batches accepted by the server, every batch once
+	WrittenBytes int64
+	// NetworkErrors counts failed requests that got no response, such as dial errors and timeouts
+	NetworkErrors int64
+	// ServerErrors counts requests failed with a 5xx status
+	ServerErrors int64
+	// ClientErrors counts requests failed with another status than 5xx, except SerializationErrors
+	ClientErrors int64
+	// SerializationErrors counts requests whose line protocol the server failed to parse
+	SerializationErrors int64
+	// RateLimited counts requests failed with 429 Too Many Requests, they are also counted as ClientErrors
+	RateLimited int64
+	// ServerTimed counts responses with the Server-Timing header or trailer, ServerTime and ServerTimedRoundTrip
+	// sum nanoseconds of the server processing and of the whole requests of these responses
+	ServerTimed          int64
+	ServerTime           int64
+	ServerTimedRoundTrip int64
+	// Datagrams counts datagrams sent by the UDP writer
+	Datagrams int64
+	// OversizePoints counts points dropped by the UDP writer because they don't fit into a datagram
+	OversizePoints int64
+}
+
+// AverageServerTime returns the average server processing time reported by the Server-Timing of responses,
+// zero when no response reported it
+func (s WriteStats) AverageServerTime() time.Duration {
+	if s.ServerTimed == 0 {
+		return 0
+	}
+	return time.Duration(s.ServerTime / s.ServerTimed)
+}
+
+// AverageServerTimedRoundTrip returns the average round-trip time of requests whose responses reported
+// Server-Timing, the difference to AverageServerTime is spent by the client and the network
+func (s WriteStats) AverageServerTimedRoundTrip() time.Duration {
+	if s.ServerTimed == 0 {
+		return 0
+	}
+	return time.Duration(s.ServerTimedRoundTrip / s.ServerTimed)
+}
+
+// WriteAmplification returns SentBytes divided by WrittenBytes, it exceeds 1 when batches are retried
+// and it is zero when nothing was written
+func (s WriteStats) WriteAmplification() float64 {
+	if s.WrittenBytes == 0 {
+		return 0
+	}
+	return float64(s.SentBytes) / float64(s.WrittenBytes)
+}
+
+// mergeWriteStats sums stats of the writers that report them
+func mergeWriteStats(writers []Writer) WriteStats {
+	var stats WriteStats
+	for _, writer := range writers {
+		if reporter, ok := writer.(StatsReporter); ok {
+			writerStats := reporter.WriteStats()
+			stats.Batches += writerStats.Batches
+			stats.FailedBatches += writerStats.FailedBatches
+			stats.PartialWrites += writerStats.PartialWrites
+			stats.DroppedPoints += writerStats.DroppedPoints
+			stats.Connections += writerStats.Connections
+			stats.SentBytes += writerStats.SentBytes
+			stats.WrittenBytes += writerStats.WrittenBytes
+			stats.NetworkErrors += writerStats.NetworkErrors
+			stats.ServerErrors += writerStats.ServerErrors
+			stats.ClientErrors += writerStats.ClientErrors
+			stats.SerializationErrors += writerStats.SerializationErrors
+			stats.RateLimited += writerStats.RateLimited
+			stats.ServerTimed += writerStats.ServerTimed
+			stats.ServerTime += writerStats.ServerTime
+			stats.ServerTimedRoundTrip += writerStats.ServerTimedRoundTrip
+			stats.Datagrams += writerStats.Datagrams
+			stats.OversizePoints += writerStats.OversizePoints
+		}
+	}
+	return stats
+}
+
+// StatsReporter is implemented by writers that count outcomes of their write requests
+type StatsReporter interface {
+	WriteStats() WriteStats
+}
+
+// countQuerier is implemented by writers that tell the query of their Count, it is empty when the writer doesn't count
+// by a query
+type countQuerier interface {
+	countQuery(measurementName string) string
+}
+
+// tagCounter is implemented by writers that count the points having tags
+type tagCounter interface {
+	countTagged(measurementName string, tags map[string]string) (int, error)
+}
+
+// CountTagged counts the points of the measurement having all the tags, like WriterConfig.DefaultTags
+func CountTagged(writer Writer, measurementName string, tags map[string]string) (int, error) {
+	if counter, ok := writer.(tagCounter); ok {
+		return counter.countTagged(measurementName, tags)
+	}
+	return 0, fmt.Errorf("the writer doesn't count tagged points")
+}
+
+// CountQuery returns the Flux or InfluxQL query Count of the writer executes to count the points of the measurement
+func CountQuery(writer Writer, measurementName string) (string, error) {
+	if querier, ok := writer.(countQuerier); ok {
+		if query := querier.countQuery(measurementName); query != "" {
+			return query, nil
+		}
+	}
+	return "", fmt.Errorf("the writer doesn't count by a query")
+}
+
+// WriterConfig holds settings used to create writers
+type WriterConfig struct {
+	AuthToken    string
+	BatchSize    uint
+	ThreadsCount int
+	Points       PointOptions
+	// BatchSizeMin and BatchSizeMax draw the size of every batch of the raw writers uniformly from the range instead
+	// of BatchSize, a zero BatchSizeMin means BatchSize and a zero BatchSizeMax disables the range
+	BatchSizeMin uint
+	BatchSizeMax uint
+	// DefaultTags are added to every point by the V2 client, the points written by WriteRecord of the "record"
+	// V2WriteMode are not changed by the client
+	DefaultTags map[string]string
+	// ExtraHeaders are added to requests of raw writers
+	ExtraHeaders http.Header
+	// DebugSampleRate is the fraction of raw writes logged with their response
+	DebugSampleRate float64
+	// CountLang is the language of the V2 count query, "flux" or "influxql"
+	CountLang string
+	// LightweightCount counts the values of the counted field by the flux count query without joining the fields
+	// of points by pivot, it is cheaper for the server. The count is the same as long as every point has the counted
+	// field, which holds for the points of the benchmark, points written without the field by other writers are missed
+	// by both queries. The InfluxQL count query counts the values of the field already.
+	LightweightCount bool
+	// CountStart and CountStop are the flux expressions of the range of the flux queries of the V2 writers, like "-1h"
+	// or "time(v: 1000)", empty means the range from the epoch to now()
+	CountStart string
+	CountStop  string
+	// CountField is the field whose values are counted, "temperature" is counted when empty
+	CountField string
+	// KeepAlive is the TCP keep-alive period of raw writer connections, negative disables the probes
+	KeepAlive time.Duration
+	// IdleConnTimeout closes raw writer connections idle for longer, zero means no limit
+	IdleConnTimeout time.Duration
+	// TokenRotateInterval rotates the token of the CLIENT_GO_V2 writer through AuthToken and RotateTokens, zero disables the rotation
+	TokenRotateInterval time.Duration
+	RotateTokens        []string
+	// BatchTrace records the timing of every batch of the writers, nil disables the trace
+	BatchTrace *BatchTrace
+	// DeadLetters receives the points dropped by failed writes of the raw and blocking writers, nil discards them
+	DeadLetters *DeadLetters
+	// Retries is the budget shared by all plain HTTP writers for retrying failed write requests, nil disables retries
+	Retries *RetryBudget
+	// OnError is the policy of failed writes of the raw and blocking writers: "requeue" puts the points back
+	// for a later attempt, "drop" discards them and "abort" stops the run, empty means "drop"
+	OnError string
+	// MaxPendingPoints bounds points pending in the asynchronous write API of the V2 client since its last flush,
+	// the points beyond the bound wait for a flush or they are dropped by PendingPolicy "drop", zero means no bound
+	MaxPendingPoints int
+	PendingPolicy    string
+	// V2WriteMode selects how the V2 client writes points, "point" by WritePoint or "record" by WriteRecord
+	// with line protocol serialized by the benchmark, empty means "point"
+	V2WriteMode string
+	// Org and Bucket are the organization and the bucket of the V2 writers, empty means "my-org" and "my-bucket"
+	Org    string
+	Bucket string
+	// Cloud addresses Org of the raw V2 writer by its ID as InfluxDB Cloud does, Org is its name otherwise
+	Cloud bool
+	// ContentType is the Content-Type header of requests of the raw writers, empty means "text/plain; charset=utf-8"
+	// and "none" sends requests without the header
+	ContentType string
+	// LineSeparator separates lines in batches of the raw writers, empty means "\n"
+	LineSeparator string
+	// WriteConsistency is the write consistency of clustered InfluxDB Enterprise of the V1 writers,
+	// "one", "quorum", "all" or "any", empty leaves the default of the server
+	WriteConsistency string
+	// Nagle enables Nagle's algorithm on TCP connections of the raw writers, Go disables it (TCP_NODELAY) by default
+	Nagle bool
+	// UDPAddr is the host:port of the UDP listener of InfluxDB 1.x of the UDP writer, empty means the host of the server
+	// URL and the default port 8089. The listener must write into the iot_writes database so that points are counted.
+	UDPAddr string
+	// UDPPayloadBytes limits the size of datagrams of the UDP writer, zero means DefaultUDPPayloadBytes
+	UDPPayloadBytes int
+	// HostHeader is the Host header of write requests of the raw writers instead of the host of the URL, so that
+	// a load balancer routes them to a node of a cluster, empty means the host of the URL. Counting is not affected.
+	HostHeader string
+	// UnixSocket is the path of the Unix domain socket dialed by the raw writers instead of TCP, empty means TCP
+	UnixSocket string
+	// ReportLockWait measures the wait of the writers for their shared locks, see Config.ReportSyncOverhead
+	ReportLockWait bool
+	// ReusePoints serializes the first point of every series once and writes it again with the timestamps of the next
+	// points, it is supported by the raw writers and the "record" V2WriteMode
+	ReusePoints bool
+	// Tracing instruments write requests of the raw writers by OpenTelemetry spans, see StartTracing
+	Tracing bool
+	// WriteTimeout bounds every write request of the raw and blocking writers, zero means no limit. Writes during
+	// the run are also bounded by the end of the run, so that late retries don't outlive it.
+	WriteTimeout time.Duration
+	// RawClient is the HTTP client of the raw writers, "nethttp" or "fasthttp", empty means "nethttp".
+	// The fasthttp client doesn't measure the connection setup, sample requests by DebugSampleRate or propagate traces.
+	RawClient string
+}
+
+// countField returns the counted field
+func (c WriterConfig) countField() string {
+	if c.CountField == "" {
+		return "temperature"
+	}
+	return c.CountField
+}
+
+// org returns the organization of the V2 writers
+func (c WriterConfig) org() string {
+	if c.Org == "" {
+		return "my-org"
+	}
+	return c.Org
+}
+
+// bucket returns the bucket of the V2 writers
+func (c WriterConfig) bucket() string {
+	if c.Bucket == "" {
+		return "my-bucket"
+	}
+	return c.Bucket
+}
+
+// countStart returns the flux start of the range of the queries of the V2 writers
+func (c WriterConfig) countStart() string {
+	if c.CountStart == "" {
+		return "0"
+	}
+	return c.CountStart
+}
+
+// countStop returns the flux stop of the range of the queries of the V2 writers
+func (c WriterConfig) countStop() string {
+	if c.CountStop == "" {
+		return "now()"
+	}
+	return c.CountStop
+}
+
+// relativeDuration matches negative flux durations like -1h or -1h30m
+var relativeDuration = regexp.MustCompile(`^-([0-9]+(ns|us|µs|ms|s|mo|m|h|d|w|y))+$`)
+
+// ParseCountRange returns CountStart and CountStop of the range of the flux count query, "epoch" is the range from
+// the epoch to now(), "window" is the range of the written points from start to stop in nanoseconds and a relative
+// start like "-1h" is the range from it to now()
+func ParseCountRange(countRange string, start int64, stop int64) (string, string, error) {
+	switch {
+	case countRange == "epoch":
+		return "", "", nil
+	case countRange == "window":
+		return fmt.Sprintf("time(v: %d)", start), fmt.Sprintf("time(v: %d)", stop), nil
+	case relativeDuration.MatchString(countRange):
+		return countRange, "", nil
+	default:
+		return "", "", fmt.Errorf("unsupported countRange: %v", countRange)
+	}
+}
+
+// v2WriteUrl returns the URL of the write endpoint of the raw V2 writer
+func (c WriterConfig) v2WriteUrl(serverUrl string) string {
+	orgParameter := "org"
+	if c.Cloud {
+		orgParameter = "orgID"
+	}
+	return serverUrl + "/api/v2/write?" + orgParameter + "=" + url.QueryEscape(c.org()) + "&bucket=" + url.QueryEscape(c.bucket()) + "&precision=" + c.Points.PrecisionV2()
+}
+
+// setContentType sets the configured Content-Type header of raw writes into headers
+func (c WriterConfig) setContentType(headers http.Header) {
+	switch c.ContentType {
+	case "":
+		headers.Set("Content-Type", "text/plain; charset=utf-8")
+	case "none":
+	default:
+		headers.Set("Content-Type", c.ContentType)
+	}
+}
+
+// ServerUrl returns serverUrl without the trailing slash or the default URL of the InfluxDB version of writerType,
+// or of a local sink for HTTP_SINK, for empty serverUrl
+func ServerUrl(writerType string, serverUrl string) string {
+	if serverUrl == "" {
+		if writerType == "HTTP_SINK" {
+			return "http://localhost:8080"
+		}
+		if strings.HasSuffix(writerType, "_V2") {
+			return "http://localhost:9999"
+		}
+		return "http://localhost:8086"
+	}
+	return strings.TrimSuffix(serverUrl, "/")
+}
+
+// CheckHealth verifies that the InfluxDB of writerType is up, by the /health endpoint for V2 and the /ping endpoint for V1
+func CheckHealth(writerType string, serverUrl string) error {
+	endpoint := healthEndpoint(writerType, serverUrl)
+	httpClient := &http.Client{Timeout: 5 * time.Second}
+	defer httpClient.CloseIdleConnections()
+	response, err := httpClient.Get(endpoint)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, response.Body)
+	if response.StatusCode/100 != 2 {
+		return fmt.Errorf("%s responded with %s", endpoint, response.Status)
+	}
+	return nil
+}
+
+// healthEndpoint returns the URL of the /health endpoint for V2 and of the /ping endpoint for V1
+func healthEndpoint(writerType string, serverUrl string) string {
+	serverUrl = ServerUrl(writerType, serverUrl)
+	if strings.HasSuffix(writerType, "_V2") {
+		return serverUrl + "/health"
+	}
+	return serverUrl + "/ping"
+}
+
+// NewWriter creates the writer of writerType that writes into serverUrl, the default URL of the InfluxDB version is used for empty serverUrl
+func NewWriter(writerType string, serverUrl string, config WriterConfig) Writer {
+	serverUrl = ServerUrl(writerType, serverUrl)
+	switch writerType {
+	case "CLIENT_GO_V2":
+		if config.TokenRotateInterval > 0 {
+			tokens := append([]string{config.AuthToken}, config.RotateTokens...)
+			return NewWriterV2Rotating(serverUrl, tokens, config.TokenRotateInterval, config)
+		}
+		return NewWriterV2(newClientV2(serverUrl, config.AuthToken, config.BatchSize, config.Points.precision(), config.DefaultTags), config)
+	case "HTTP_GO_V1":
+		headers := http.Header{}
+		config.setContentType(headers)
+		addHeaders(headers, config.ExtraHeaders)
+		writeUrl := serverUrl + "/write?db=iot_writes"
+		if config.WriteConsistency != "" {
+			writeUrl += "&consistency=" + config.WriteConsistency
+		}
+		if precision := config.Points.PrecisionV1(); precision != "" {
+			writeUrl += "&precision=" + precision
+		}
+		return NewWriterHTTP(writeUrl, headers, config,
+			NewWriterV1(newClientV1(serverUrl), config))
+	case "HTTP_GO_V2":
+		headers := http.Header{}
+		config.setContentType(headers)
+		headers.Set("Authorization", "Token "+config.AuthToken)
+		addHeaders(headers, config.ExtraHeaders)
+		return NewWriterHTTP(config.v2WriteUrl(serverUrl), headers, config,
+			NewWriterV2(newClientV2(serverUrl, config.AuthToken, config.BatchSize, config.Points.precision(), config.DefaultTags), config))
+	case "HTTP_SINK":
+		return NewWriterSink(serverUrl, config)
+	case "UDP_V1":
+		return NewWriterUDP(udpAddr(serverUrl, config), config, NewWriterV1(newClientV1(serverUrl), config))
+	default:
+		return NewWriterV1(newClientV1(serverUrl), config)
+	}
+}
+
+// addHeaders sets the extra headers into headers
+func addHeaders(headers http.Header, extra http.Header) {
+	for key, values := range extra {
+		headers[key] = values
+	}
+}
+
+func newClientV1(serverUrl string) client.Client {
+	influx, err := client.NewHTTPClient(client.HTTPConfig{
+		Addr: serverUrl,
+	})
+	if err != nil {
+		panic(err)
+	}
+	return influx
+}
+
+func newClientV2(serverUrl string, authToken string, batchSize uint, precision time.Duration, defaultTags map[string]string) influxdb2.Client {
+	options := influxdb2.DefaultOptions().SetBatchSize(batchSize).SetPrecision(precision)
+	for key, value := range defaultTags {
+		options.AddDefaultTag(key, value)
+	}
+	return influxdb2.NewClientWithOptions(serverUrl, authToken, options)
+}
@@ -0,0 +1,633 @@
+package bench
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/valyala/fasthttp"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WriterHTTP writes line protocol by plain HTTP requests without any client library.
+// Points are buffered and the batch is posted by the thread that fills it up.
+type WriterHTTP struct {
+	httpClient *http.Client
+	// fastClient sends the requests instead of httpClient with the "fasthttp" RawClient, it is nil otherwise
+	fastClient *fasthttp.Client
+	writeUrl   string
+	headers    http.Header
+	batchSize  int
+	points     PointOptions
+	// sizes draws the size of every batch with WriterConfig.BatchSizeMax, it is nil otherwise
+	sizes *batchSizes
+	// target is the size of the buffered batch
+	target int
+	// host is the Host header of WriterConfig.HostHeader, it is empty for the host of writeUrl
+	host string
+	// separator ends every line of the batches
+	separator []byte
+	// reused are the lines of WriterConfig.ReusePoints, it is nil otherwise
+	reused *reusedLines
+	// debugSampleRate is the fraction of write requests logged with their response
+	debugSampleRate float64
+	// counter is an official client writer used only to count written points
+	counter Writer
+	lock    sync.Mutex
+	// locks measures the wait for lock, it is nil without WriterConfig.ReportLockWait
+	locks    *lockTimer
+	buffer   []byte
+	buffered int
+	stats    WriteStats
+	// sentPoints counts points of batches accepted by the server
+	sentPoints int64
+	// connection measures the setup of the first connection
+	connection connectionTrace
+	retries    *RetryBudget
+	onError    *errorPolicy
+	batches    *BatchTrace
+	// deadLetters receives the points of batches that are not requeued after they failed
+	deadLetters *DeadLetters
+	// tracer creates a span of every sent batch, it is nil without tracing
+	tracer trace.Tracer
+	// deadline bounds every sent batch including its retries
+	deadline writeDeadline
+	// retryAt is the time in Unix nanoseconds from which requests can be sent again, it is set by the Retry-After
+	// header of rate-limited responses and it is updated atomically
+	retryAt int64
+}
+
+func NewWriterHTTP(writeUrl string, headers http.Header, config WriterConfig, counter Writer) *WriterHTTP {
+	p := &WriterHTTP{
+		writeUrl:        writeUrl,
+		headers:         headers,
+		host:            config.HostHeader,
+		batchSize:       int(config.BatchSize),
+		points:          config.Points,
+		debugSampleRate: config.DebugSampleRate,
+		counter:         counter,
+		retries:         config.Retries,
+		batches:         config.BatchTrace,
+		deadLetters:     config.DeadLetters,
+		onError:         newErrorPolicy(config.OnError),
+		deadline:        writeDeadline{timeout: config.WriteTimeout},
+		separator:       []byte("\n"),
+		locks:           newLockTimer(config),
+		reused:          newReusedLines(config),
+		sizes:           newBatchSizes(config),
+	}
+	p.target = p.sizes.next(0, p.batchSize)
+	if config.LineSeparator != "" {
+		p.separator = []byte(config.LineSeparator)
+	}
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: config.KeepAlive,
+	}
+	if config.RawClient == "fasthttp" {
+		p.fastClient = &fasthttp.Client{
+			Dial: func(addr string) (net.Conn, error) {
+				network := "tcp"
+				if config.UnixSocket != "" {
+					network, addr = "unix", config.UnixSocket
+				}
+				conn, err := dialer.Dial(network, addr)
+				if err == nil {
+					atomic.AddInt64(&p.stats.Connections, 1)
+					err = setNoDelay(conn, !config.Nagle)
+				}
+				return conn, err
+			},
+			MaxConnsPerHost:     config.ThreadsCount,
+			MaxIdleConnDuration: config.IdleConnTimeout,
+		}
+		if config.Tracing {
+			p.tracer = otel.Tracer(tracerName)
+		}
+		return p
+	}
+	var transport http.RoundTripper = &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if config.UnixSocket != "" {
+				network, addr = "unix", config.UnixSocket
+			}
+			conn, err := dialer.DialContext(ctx, network, addr)
+			if err == nil {
+				atomic.AddInt64(&p.stats.Connections, 1)
+				err = setNoDelay(conn, !config.Nagle)
+			}
+			return conn, err
+		},
+		MaxIdleConnsPerHost: config.ThreadsCount,
+		IdleConnTimeout:     config.IdleConnTimeout,
+	}
+	if config.Tracing {
+		p.tracer = otel.Tracer(tracerName)
+		transport = otelhttp.NewTransport(transport)
+	}
+	p.httpClient = &http.Client{Transport: transport}
+	return p
+}
+
+// setNoDelay sets TCP_NODELAY of TCP connections, other connections are left intact
+func setNoDelay(conn net.Conn, noDelay bool) error {
+	if tcp, ok := conn.(*net.TCPConn); ok {
+		return tcp.SetNoDelay(noDelay)
+	}
+	return nil
+}
+
+func (p *WriterHTTP) Write(id int, measurementName string, iterations []int) {
+	var lines []byte
+	for _, iteration := range iterations {
+		lines = p.reused.appendLine(lines, p.points, id, measurementName, iteration)
+		lines = append(lines[:len(lines)-1], p.separator...)
+	}
+
+	p.locks.lock(&p.lock)
+	p.buffer = append(p.buffer, lines...)
+	p.buffered += len(iterations)
+	if p.buffered < p.target {
+		p.lock.Unlock()
+		return
+	}
+	p.target = p.sizes.next(p.buffered, p.batchSize)
+	batch, points := p.buffer, p.buffered
+	p.buffer = make([]byte, 0, len(batch))
+	p.buffered = 0
+	p.lock.Unlock()
+
+	if err := p.send(batch, points); err != nil {
+		p.failed(batch, points, err)
+	}
+}
+
+// failed applies the OnError policy to the batch of points that failed to be sent
+func (p *WriterHTTP) failed(batch []byte, points int, err error) {
+	if p.onError.failed(points, err) {
+		p.locks.lock(&p.lock)
+		p.buffer = append(batch, p.buffer...)
+		p.buffered += points
+		p.lock.Unlock()
+	} else {
+		p.deadLetters.add(p.newlines(batch), points, err)
+	}
+}
+
+// newlines returns the batch with its lines ended by newlines instead of the separator
+func (p *WriterHTTP) newlines(batch []byte) []byte {
+	if string(p.separator) == "\n" {
+		return batch
+	}
+	return bytes.ReplaceAll(batch, p.separator, []byte("\n"))
+}
+
+// WriteLines sends the lines as one batch
+func (p *WriterHTTP) WriteLines(lines []string) error {
+	return p.send([]byte(strings.Join(lines, string(p.separator))+string(p.separator)), len(lines))
+}
+
+func (p *WriterHTTP) ErrorStats() ErrorStats {
+	return p.onError.stats()
+}
+
+func (p *WriterHTTP) aborted() <-chan bool {
+	return p.onError.aborted()
+}
+
+func (p *WriterHTTP) lockWait() time.Duration {
+	return p.locks.total()
+}
+
+func (p *WriterHTTP) runEnds(end time.Time) {
+	p.deadline.runEnds(end)
+}
+
+// send posts the batch of points and retries failed requests after a backoff while the retry budget and the deadline last
+func (p *WriterHTTP) send(batch []byte, points int) (err error) {
+	ctx, cancel := p.deadline.context(context.Background())
+	defer cancel()
+	if p.tracer != nil {
+		var span trace.Span
+		ctx, span = p.tracer.Start(ctx, "write batch", trace.WithAttributes(
+			attribute.Int("batch.points", points),
+			attribute.Int("batch.bytes", len(batch)),
+		))
+		defer func() {
+			if err != nil {
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}()
+	}
+	start := time.Now()
+	attempts := 1
+	err = p.post(ctx, batch, points)
+	for err != nil && ctx.Err() == nil && p.retries != nil && p.retries.take() {
+		if backoffErr := p.backoff(ctx, attempts); backoffErr != nil {
+			// the deadline passed while waiting, the batch failed by the error of its last attempt
+			break
+		}
+		attempts++
+		err = p.post(ctx, batch, points)
+	}
+	p.batches.record("batch", start, points, len(batch), attempts, err)
+	return err
+}
+
+// post posts the batch of points in line protocol to the write endpoint, partial writes are counted separately from
+// hard errors. The batch is not posted until the Retry-After of the last rate-limited request passes.
+func (p *WriterHTTP) post(ctx context.Context, batch []byte, points int) error {
+	if err := p.waitRetryAfter(ctx); err != nil {
+		return err
+	}
+	atomic.AddInt64(&p.stats.Batches, 1)
+	atomic.AddInt64(&p.stats.SentBytes, int64(len(batch)))
+	var status string
+	var statusCode int
+	var body []byte
+	var header http.Header
+	var err error
+	start := time.Now()
+	if p.fastClient != nil {
+		statusCode, body, header, err = p.postFast(ctx, batch)
+		status = fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode))
+	} else {
+		var resp *http.Response
+		resp, body, err = p.postNet(ctx, batch)
+		if resp != nil {
+			status, statusCode, header = resp.Status, resp.StatusCode, resp.Header.Clone()
+			for key, values := range resp.Trailer {
+				header[key] = append(header[key], values...)
+			}
+		}
+	}
+	if err != nil {
+		atomic.AddInt64(&p.stats.FailedBatches, 1)
+		atomic.AddInt64(&p.stats.NetworkErrors, 1)
+		return err
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int("http.response.status_code", statusCode))
+	if serverTime, ok := serverTiming(header.Values("Server-Timing")); ok {
+		atomic.AddInt64(&p.stats.ServerTimed, 1)
+		atomic.AddInt64(&p.stats.ServerTime, int64(serverTime))
+		atomic.AddInt64(&p.stats.ServerTimedRoundTrip, int64(time.Since(start)))
+	}
+	if statusCode < 200 || statusCode >= 300 {
+		atomic.AddInt64(&p.stats.FailedBatches, 1)
+		atomic.AddInt64(p.stats.statusErrors(statusCode, body), 1)
+		if statusCode == http.StatusTooManyRequests {
+			atomic.AddInt64(&p.stats.RateLimited, 1)
+			p.rateLimited(header.Get("Retry-After"))
+		}
+		if message := bytes.TrimSpace(body); len(message) > 0 {
+			// the body tells why the server rejected the batch
+			return fmt.Errorf("write failed: %s: %s", status, message)
+		}
+		return fmt.Errorf("write failed: %s", status)
+	}
+	atomic.AddInt64(&p.stats.WrittenBytes, int64(len(batch)))
+	if partial, dropped := partialWrite(body); partial {
+		// the accepted batch is not a failure, only its dropped points were not written
+		atomic.AddInt64(&p.stats.PartialWrites, 1)
+		atomic.AddInt64(&p.stats.DroppedPoints, dropped)
+		atomic.AddInt64(&p.sentPoints, int64(points)-dropped)
+		return nil
+	}
+	atomic.AddInt64(&p.sentPoints, int64(points))
+	return nil
+}
+
+// postNet posts the batch by net/http and reads the response body, the request is sampled by debugSampleRate
+func (p *WriterHTTP) postNet(ctx context.Context, batch []byte) (resp *http.Response, body []byte, err error) {
+	ctx, traced := p.connection.trace(ctx)
+	defer func() { traced(err) }()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.writeUrl, bytes.NewReader(batch))
+	if err != nil {
+		return nil, nil, err
+	}
+	for key, values := range p.headers {
+		req.Header[key] = values
+	}
+	if p.host != "" {
+		// net/http sends req.Host instead of the Host of req.Header
+		req.Host = p.host
+	}
+	sampled := p.debugSampleRate > 0 && rand.Float64() < p.debugSampleRate
+	resp, err = p.httpClient.Do(req)
+	if err != nil {
+		if sampled {
+			logSample(req, batch, nil, nil, err)
+		}
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	body, err = ioutil.ReadAll(resp.Body)
+	if sampled {
+		logSample(req, batch, resp, body, err)
+	}
+	return resp, body, err
+}
+
+// postFast posts the batch by fasthttp and returns the status, the body and the Retry-After and Server-Timing headers
+// of the response, the connection setup is not measured and requests are not sampled
+func (p *WriterHTTP) postFast(ctx context.Context, batch []byte) (int, []byte, http.Header, error) {
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+	req.SetRequestURI(p.writeUrl)
+	req.Header.SetMethod(http.MethodPost)
+	// the Content-Type is sent only when it is among the headers
+	req.Header.SetNoDefaultContentType(true)
+	for key, values := range p.headers {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	if p.host != "" {
+		// the connection is dialed to the host of the URL anyway
+		req.UseHostHeader = true
+		req.Header.SetHost(p.host)
+	}
+	req.SetBodyRaw(batch)
+	var err error
+	if deadline, ok := ctx.Deadline(); ok {
+		err = p.fastClient.DoDeadline(req, resp, deadline)
+	} else {
+		err = p.fastClient.Do(req, resp)
+	}
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	header := make(http.Header)
+	for _, key := range []string{"Retry-After", "Server-Timing"} {
+		for _, value := range resp.Header.PeekAll(key) {
+			header.Add(key, string(value))
+		}
+	}
+	return resp.StatusCode(), append([]byte(nil), resp.Body()...), header, nil
+}
+
+// serverTiming returns the server processing time of the Server-Timing values, that is the duration of the total
+// metric, or the longest duration when there is no total metric
+func serverTiming(values []string) (time.Duration, bool) {
+	var longest, total float64
+	found, foundTotal := false, false
+	for _, value := range values {
+		for _, metric := range strings.Split(value, ",") {
+			params := strings.Split(metric, ";")
+			for _, param := range params[1:] {
+				param = strings.TrimSpace(param)
+				if !strings.HasPrefix(param, "dur=") {
+					continue
+				}
+				dur, err := strconv.ParseFloat(strings.Trim(strings.TrimPrefix(param, "dur="), `"`), 64)
+				if err != nil || dur < 0 {
+					continue
+				}
+				found = true
+				if dur > longest {
+					longest = dur
+				}
+				if strings.TrimSpace(params[0]) == "total" {
+					total, foundTotal = dur, true
+				}
+			}
+		}
+	}
+	if foundTotal {
+		longest = total
+	}
+	// durations are in milliseconds
+	return time.Duration(longest * float64(time.Millisecond)), found
+}
+
+// rateLimited postpones requests by the Retry-After header in seconds or as an HTTP date, it is ignored when not valid
+func (p *WriterHTTP) rateLimited(retryAfter string) {
+	var at time.Time
+	if seconds, err := strconv.Atoi(retryAfter); err == nil {
+		at = time.Now().Add(time.Duration(seconds) * time.Second)
+	} else if date, err := http.ParseTime(retryAfter); err == nil {
+		at = date
+	} else {
+		return
+	}
+	for {
+		current := atomic.LoadInt64(&p.retryAt)
+		if at.UnixNano() <= current || atomic.CompareAndSwapInt64(&p.retryAt, current, at.UnixNano()) {
+			return
+		}
+	}
+}
+
+// waitRetryAfter waits until requests can be sent again, it fails when the context is done first
+func (p *WriterHTTP) waitRetryAfter(ctx context.Context) error {
+	return sleepContext(ctx, time.Until(time.Unix(0, atomic.LoadInt64(&p.retryAt))))
+}
+
+// backoff waits before retrying the failed attempt by the exponential backoff, or until the Retry-After
+// of the last rate-limited request when it is later, it fails when the context is done first
+func (p *WriterHTTP) backoff(ctx context.Context, attempt int) error {
+	delay := retryBackoff(attempt)
+	if retryAfter := time.Until(time.Unix(0, atomic.LoadInt64(&p.retryAt))); retryAfter > delay {
+		delay = retryAfter
+	}
+	return sleepContext(ctx, delay)
+}
+
+// parseError matches responses of InfluxDB rejecting line protocol it cannot parse
+var parseError = regexp.MustCompile(`unable to parse|bad timestamp|invalid field format|missing fields|invalid boolean|invalid number`)
+
+// statusErrors returns the counter of the category of the failed status, the body tells serialization errors
+// apart from other client errors
+func (s *WriteStats) statusErrors(statusCode int, body []byte) *int64 {
+	switch {
+	case statusCode >= 500:
+		return &s.ServerErrors
+	case statusCode == http.StatusBadRequest && parseError.Match(body):
+		return &s.SerializationErrors
+	default:
+		return &s.ClientErrors
+	}
+}
+
+func (p *WriterHTTP) WriteStats() WriteStats {
+	return WriteStats{
+		Batches:              atomic.LoadInt64(&p.stats.Batches),
+		FailedBatches:        atomic.LoadInt64(&p.stats.FailedBatches),
+		PartialWrites:        atomic.LoadInt64(&p.stats.PartialWrites),
+		DroppedPoints:        atomic.LoadInt64(&p.stats.DroppedPoints),
+		Connections:          atomic.LoadInt64(&p.stats.Connections),
+		SentBytes:            atomic.LoadInt64(&p.stats.SentBytes),
+		WrittenBytes:         atomic.LoadInt64(&p.stats.WrittenBytes),
+		NetworkErrors:        atomic.LoadInt64(&p.stats.NetworkErrors),
+		ServerErrors:         atomic.LoadInt64(&p.stats.ServerErrors),
+		ClientErrors:         atomic.LoadInt64(&p.stats.ClientErrors),
+		SerializationErrors:  atomic.LoadInt64(&p.stats.SerializationErrors),
+		RateLimited:          atomic.LoadInt64(&p.stats.RateLimited),
+		ServerTimed:          atomic.LoadInt64(&p.stats.ServerTimed),
+		ServerTime:           atomic.LoadInt64(&p.stats.ServerTime),
+		ServerTimedRoundTrip: atomic.LoadInt64(&p.stats.ServerTimedRoundTrip),
+	}
+}
+
+func (p *WriterHTTP) Connections() (int64, bool) {
+	return atomic.LoadInt64(&p.stats.Connections), true
+}
+
+func (p *WriterHTTP) batchSizes() []int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.sizes.recorded()
+}
+
+func (p *WriterHTTP) ConnectionSetup() ConnectionSetup {
+	return p.connection.connectionSetup()
+}
+
+// flush sends all buffered points
+func (p *WriterHTTP) flush() error {
+	p.lock.Lock()
+	if p.buffered > 0 {
+		p.target = p.sizes.next(p.buffered, p.batchSize)
+	}
+	batch, points := p.buffer, p.buffered
+	p.buffer = nil
+	p.buffered = 0
+	p.lock.Unlock()
+
+	if len(batch) > 0 {
+		err := p.send(batch, points)
+		if err != nil {
+			p.failed(batch, points, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// Flush sends all buffered points, a failed batch is counted by the stats and handled by the OnError policy
+func (p *WriterHTTP) Flush() {
+	_ = p.flush()
+}
+
+// Count counts the points once the buffered ones are sent, a failure of their batch doesn't fail the count
+func (p *WriterHTTP) Count(measurementName string) (int, error) {
+	p.Flush()
+	return p.counter.Count(measurementName)
+}
+
+func (p *WriterHTTP) readCount(measurementName string) (int, error) {
+	return p.counter.Count(measurementName)
+}
+
+func (p *WriterHTTP) seriesTimes(measurementName string, id int) ([]int64, error) {
+	querier, ok := p.counter.(timeQuerier)
+	if !ok {
+		return nil, fmt.Errorf("the counter doesn't query timestamps")
+	}
+	return querier.seriesTimes(measurementName, id)
+}
+
+func (p *WriterHTTP) timeRange(measurementName string) (int64, int64, error) {
+	querier, ok := p.counter.(timeQuerier)
+	if !ok {
+		return 0, 0, fmt.Errorf("the counter doesn't query timestamps")
+	}
+	return querier.timeRange(measurementName)
+}
+
+func (p *WriterHTTP) countQuery(measurementName string) string {
+	if querier, ok := p.counter.(countQuerier); ok {
+		return querier.countQuery(measurementName)
+	}
+	return ""
+}
+
+func (p *WriterHTTP) countRange(measurementName string, start int64, stop int64) (int, error) {
+	counter, ok := p.counter.(rangeCounter)
+	if !ok {
+		return 0, fmt.Errorf("the counter doesn't count time ranges")
+	}
+	return counter.countRange(measurementName, start, stop)
+}
+
+func (p *WriterHTTP) Close() error {
+	err := p.flush()
+	if err != nil {
+		// the points requeued by the failed flush are never sent
+		p.lock.Lock()
+		batch, buffered := p.buffer, p.buffered
+		p.buffer, p.buffered = nil, 0
+		p.lock.Unlock()
+		p.deadLetters.add(p.newlines(batch), buffered, err)
+	}
+	if p.fastClient != nil {
+		p.fastClient.CloseIdleConnections()
+	} else {
+		p.httpClient.CloseIdleConnections()
+	}
+	if closeErr := p.counter.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+var droppedPointsPattern = regexp.MustCompile(`dropped=(\d+)`)
+
+// partialWrite checks whether the response body reports a partial write and returns the count of dropped points
+func partialWrite(body []byte) (bool, int64) {
+	if !bytes.Contains(bytes.ToLower(body), []byte("partial write")) {
+		return false, 0
+	}
+	var dropped int64
+	for _, match := range droppedPointsPattern.FindAllSubmatch(body, -1) {
+		count, _ := strconv.ParseInt(string(match[1]), 10, 64)
+		dropped += count
+	}
+	return true, dropped
+}
+
+// logSample prints the sampled write request and its response into stderr, the authorization header is redacted
+func logSample(req *http.Request, batch []byte, resp *http.Response, body []byte, err error) {
+	var sample strings.Builder
+	fmt.Fprintf(&sample, "\n>>> %s %s\n", req.Method, req.URL)
+	keys := make([]string, 0, len(req.Header))
+	for key := range req.Header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := strings.Join(req.Header[key], ", ")
+		if key == "Authorization" {
+			value = "<redacted>"
+		}
+		fmt.Fprintf(&sample, "%s: %s\n", key, value)
+	}
+	sample.Write(batch)
+	if resp != nil {
+		fmt.Fprintf(&sample, "<<< %s\n", resp.Status)
+		sample.Write(body)
+		sample.WriteString("\n")
+	}
+	if err != nil {
+		fmt.Fprintf(&sample, "<<< error: %v\n", err)
+	}
+	_, _ = os.Stderr.WriteString(sample.String())
+}
@@ -0,0 +1,59 @@
+package bench
+
+import (
+	"testing"
+	"time"
+)
+
+func TestServerTiming(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		want   time.Duration
+		found  bool
+	}{
+		{"no header", nil, 0, false},
+		{"empty value", []string{""}, 0, false},
+		{"one metric", []string{"db;dur=12.5"}, 12500 * time.Microsecond, true},
+		{"quoted duration", []string{`db;dur="7"`}, 7 * time.Millisecond, true},
+		{"description", []string{`db;desc="query";dur=3`}, 3 * time.Millisecond, true},
+		{"longest metric", []string{"db;dur=5, cache;dur=30,parse;dur=1"}, 30 * time.Millisecond, true},
+		{"total metric", []string{"db;dur=5, total;dur=20, cache;dur=30"}, 20 * time.Millisecond, true},
+		{"several values", []string{"db;dur=1", "total;dur=4"}, 4 * time.Millisecond, true},
+		{"no duration", []string{`cache;desc="hit"`}, 0, false},
+		{"name only", []string{"dur=5"}, 0, false},
+		{"malformed duration", []string{"db;dur=abc"}, 0, false},
+		{"negative duration", []string{"db;dur=-1"}, 0, false},
+		{"malformed and valid", []string{"db;dur=x, cache;dur=2"}, 2 * time.Millisecond, true},
+	}
+	for _, test := range tests {
+		got, found := serverTiming(test.values)
+		if got != test.want || found != test.found {
+			t.Errorf("%s: serverTiming(%q) = %v, %v, want %v, %v", test.name, test.values, got, found, test.want, test.found)
+		}
+	}
+}
+
+func TestPartialWrite(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		partial bool
+		dropped int64
+	}{
+		{"empty body", "", false, 0},
+		{"other error", `{"code":"invalid","message":"unable to parse 'cpu value=': missing field value"}`, false, 0},
+		{"V2 partial write", `{"code":"unprocessable entity","message":"partial write: field type conflict: input field \"temp\" on measurement \"cpu\" is type string, already exists as type float dropped=3"}`, true, 3},
+		{"V1 partial write", `{"error":"partial write: points beyond retention policy dropped=2"}`, true, 2},
+		{"several drops", `{"error":"partial write: field type conflict dropped=1\npartial write: points beyond retention policy dropped=4"}`, true, 5},
+		{"upper case", `{"error":"Partial Write: dropped=6"}`, true, 6},
+		{"no count", `{"error":"partial write: max-values-per-tag limit exceeded"}`, true, 0},
+		{"count without partial write", `{"error":"dropped=3"}`, false, 0},
+	}
+	for _, test := range tests {
+		partial, dropped := partialWrite([]byte(test.body))
+		if partial != test.partial || dropped != test.dropped {
+			t.Errorf("%s: partialWrite = %v, %v, want %v, %v", test.name, partial, dropped, test.partial, test.dropped)
+		}
+	}
+}
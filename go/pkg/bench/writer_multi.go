@@ -0,0 +1,191 @@
+package bench
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// WriterMulti distributes threads round-robin across writers of multiple endpoints
+type WriterMulti struct {
+	urls    []string
+	writers []Writer
+	// writes counts written points per endpoint, it is updated atomically
+	writes []int64
+	// abort is closed when any of the writers aborts the run
+	abort <-chan bool
+}
+
+func NewWriterMulti(urls []string, writers []Writer) *WriterMulti {
+	return &WriterMulti{
+		urls:    urls,
+		writers: writers,
+		writes:  make([]int64, len(writers)),
+		abort:   mergeAborted(writers),
+	}
+}
+
+// Urls returns the endpoints of the writers
+func (p *WriterMulti) Urls() []string {
+	return p.urls
+}
+
+// Writes returns the count of points written into the endpoint of index
+func (p *WriterMulti) Writes(index int) int64 {
+	return atomic.LoadInt64(&p.writes[index])
+}
+
+func (p *WriterMulti) Write(id int, measurementName string, iterations []int) {
+	index := (id - 1) % len(p.writers)
+	p.writers[index].Write(id, measurementName, iterations)
+	atomic.AddInt64(&p.writes[index], int64(len(iterations)))
+}
+
+// Flush writes points buffered by the writers of all endpoints
+func (p *WriterMulti) Flush() {
+	for _, writer := range p.writers {
+		if writer, ok := writer.(flusher); ok {
+			writer.Flush()
+		}
+	}
+}
+
+func (p *WriterMulti) lockWait() time.Duration {
+	return sumLockWait(p.writers)
+}
+
+func (p *WriterMulti) runEnds(end time.Time) {
+	for _, writer := range p.writers {
+		if writer, ok := writer.(runEnder); ok {
+			writer.runEnds(end)
+		}
+	}
+}
+
+func (p *WriterMulti) ErrorStats() ErrorStats {
+	return mergeErrorStats(p.writers)
+}
+
+func (p *WriterMulti) aborted() <-chan bool {
+	return p.abort
+}
+
+func (p *WriterMulti) PendingStats() PendingStats {
+	return mergePendingStats(p.writers)
+}
+
+func (p *WriterMulti) Count(measurementName string) (int, error) {
+	total := 0
+	for i, writer := range p.writers {
+		count, err := writer.Count(measurementName)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %v", p.urls[i], err)
+		}
+		total += count
+	}
+	return total, nil
+}
+
+func (p *WriterMulti) readCount(measurementName string) (int, error) {
+	total := 0
+	for i, writer := range p.writers {
+		count, err := readCount(writer, measurementName)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %v", p.urls[i], err)
+		}
+		total += count
+	}
+	return total, nil
+}
+
+// seriesTimes queries the endpoint the series id is written into
+func (p *WriterMulti) seriesTimes(measurementName string, id int) ([]int64, error) {
+	index := (id - 1) % len(p.writers)
+	querier, ok := p.writers[index].(timeQuerier)
+	if !ok {
+		return nil, fmt.Errorf("%s: the writer doesn't query timestamps", p.urls[index])
+	}
+	return querier.seriesTimes(measurementName, id)
+}
+
+// timeRange returns the first and the last timestamp of the points of all endpoints
+func (p *WriterMulti) timeRange(measurementName string) (int64, int64, error) {
+	var first, last int64
+	for i, writer := range p.writers {
+		querier, ok := writer.(timeQuerier)
+		if !ok {
+			return 0, 0, fmt.Errorf("%s: the writer doesn't query timestamps", p.urls[i])
+		}
+		start, stop, err := querier.timeRange(measurementName)
+		if err != nil {
+			return 0, 0, fmt.Errorf("%s: %v", p.urls[i], err)
+		}
+		if i == 0 || start < first {
+			first = start
+		}
+		if i == 0 || stop > last {
+			last = stop
+		}
+	}
+	return first, last, nil
+}
+
+// countRange sums the counts of the time range of all endpoints
+func (p *WriterMulti) countRange(measurementName string, start int64, stop int64) (int, error) {
+	total := 0
+	for i, writer := range p.writers {
+		counter, ok := writer.(rangeCounter)
+		if !ok {
+			return 0, fmt.Errorf("%s: the writer doesn't count time ranges", p.urls[i])
+		}
+		count, err := counter.countRange(measurementName, start, stop)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %v", p.urls[i], err)
+		}
+		total += count
+	}
+	return total, nil
+}
+
+func (p *WriterMulti) batchSizes() []int {
+	return mergeBatchSizes(p.writers)
+}
+
+// countTagged sums the counts of the tagged points of all endpoints
+func (p *WriterMulti) countTagged(measurementName string, tags map[string]string) (int, error) {
+	total := 0
+	for i, writer := range p.writers {
+		count, err := CountTagged(writer, measurementName, tags)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %v", p.urls[i], err)
+		}
+		total += count
+	}
+	return total, nil
+}
+
+// countQuery returns the query of the first endpoint, the query of the others is the same
+func (p *WriterMulti) countQuery(measurementName string) string {
+	if querier, ok := p.writers[0].(countQuerier); ok {
+		return querier.countQuery(measurementName)
+	}
+	return ""
+}
+
+func (p *WriterMulti) Connections() (int64, bool) {
+	return sumConnections(p.writers)
+}
+
+func (p *WriterMulti) WriteStats() WriteStats {
+	return mergeWriteStats(p.writers)
+}
+
+func (p *WriterMulti) Close() error {
+	var err error
+	for _, writer := range p.writers {
+		if closeErr := writer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,144 @@
+package bench
+
+import (
+	"fmt"
+	"time"
+)
+
+// WriterPerWorker gives every thread its own writer with its own client instead of sharing one,
+// all writers write into the same server
+type WriterPerWorker struct {
+	writers []Writer
+	// abort is closed when any of the writers aborts the run
+	abort <-chan bool
+}
+
+// NewWriterPerWorker creates the writer of threads with ids 1 to len(writers)
+func NewWriterPerWorker(writers []Writer) *WriterPerWorker {
+	return &WriterPerWorker{writers: writers, abort: mergeAborted(writers)}
+}
+
+func (p *WriterPerWorker) Write(id int, measurementName string, iterations []int) {
+	p.writers[id-1].Write(id, measurementName, iterations)
+}
+
+// Flush writes points buffered by the writers of all threads
+func (p *WriterPerWorker) Flush() {
+	for _, writer := range p.writers {
+		if writer, ok := writer.(flusher); ok {
+			writer.Flush()
+		}
+	}
+}
+
+func (p *WriterPerWorker) lockWait() time.Duration {
+	return sumLockWait(p.writers)
+}
+
+func (p *WriterPerWorker) runEnds(end time.Time) {
+	for _, writer := range p.writers {
+		if writer, ok := writer.(runEnder); ok {
+			writer.runEnds(end)
+		}
+	}
+}
+
+func (p *WriterPerWorker) ErrorStats() ErrorStats {
+	return mergeErrorStats(p.writers)
+}
+
+func (p *WriterPerWorker) aborted() <-chan bool {
+	return p.abort
+}
+
+func (p *WriterPerWorker) PendingStats() PendingStats {
+	return mergePendingStats(p.writers)
+}
+
+// Count counts by the first writer once the others have sent their buffered points
+func (p *WriterPerWorker) Count(measurementName string) (int, error) {
+	for _, writer := range p.writers[1:] {
+		if writer, ok := writer.(flusher); ok {
+			writer.Flush()
+		}
+	}
+	return p.writers[0].Count(measurementName)
+}
+
+func (p *WriterPerWorker) readCount(measurementName string) (int, error) {
+	return readCount(p.writers[0], measurementName)
+}
+
+func (p *WriterPerWorker) batchSizes() []int {
+	return mergeBatchSizes(p.writers)
+}
+
+func (p *WriterPerWorker) seriesTimes(measurementName string, id int) ([]int64, error) {
+	querier, ok := p.writers[0].(timeQuerier)
+	if !ok {
+		return nil, fmt.Errorf("the writer doesn't query timestamps")
+	}
+	return querier.seriesTimes(measurementName, id)
+}
+
+func (p *WriterPerWorker) timeRange(measurementName string) (int64, int64, error) {
+	querier, ok := p.writers[0].(timeQuerier)
+	if !ok {
+		return 0, 0, fmt.Errorf("the writer doesn't query timestamps")
+	}
+	return querier.timeRange(measurementName)
+}
+
+func (p *WriterPerWorker) countRange(measurementName string, start int64, stop int64) (int, error) {
+	counter, ok := p.writers[0].(rangeCounter)
+	if !ok {
+		return 0, fmt.Errorf("the writer doesn't count time ranges")
+	}
+	return counter.countRange(measurementName, start, stop)
+}
+
+func (p *WriterPerWorker) countTagged(measurementName string, tags map[string]string) (int, error) {
+	return CountTagged(p.writers[0], measurementName, tags)
+}
+
+func (p *WriterPerWorker) countQuery(measurementName string) string {
+	if querier, ok := p.writers[0].(countQuerier); ok {
+		return querier.countQuery(measurementName)
+	}
+	return ""
+}
+
+func (p *WriterPerWorker) Connections() (int64, bool) {
+	return sumConnections(p.writers)
+}
+
+// sumConnections sums the connections of the writers, they are not counted unless all writers count them
+func sumConnections(writers []Writer) (int64, bool) {
+	var total int64
+	for _, writer := range writers {
+		counter, ok := writer.(ConnectionCounter)
+		if !ok {
+			return 0, false
+		}
+		connections, counted := counter.Connections()
+		if !counted {
+			return 0, false
+		}
+		total += connections
+	}
+	return total, true
+}
+
+func (p *WriterPerWorker) WriteStats() WriteStats {
+	return mergeWriteStats(p.writers)
+}
+
+func (p *WriterPerWorker) Close() error {
+	var err error
+	for _, writer := range p.writers {
+		if closeErr := writer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,212 @@
+package bench
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultUDPPayloadBytes is the payload size of datagrams of the UDP writer when WriterConfig.UDPPayloadBytes is zero,
+// it is the default of the UDP client of influxdb1-client
+const DefaultUDPPayloadBytes = 512
+
+// WriterUDP writes line protocol into the UDP listener of InfluxDB 1.x. Points are buffered like by WriterHTTP and
+// every batch is split into datagrams of whole lines of at most payloadBytes, a line longer than payloadBytes can't be
+// sent and it is dropped. The listener doesn't answer, so datagrams lost on the way show only in the count of counter.
+type WriterUDP struct {
+	conn         net.Conn
+	payloadBytes int
+	batchSize    int
+	points       PointOptions
+	// sizes draws the size of every batch with WriterConfig.BatchSizeMax, it is nil otherwise
+	sizes *batchSizes
+	// target is the size of the buffered batch
+	target int
+	// counter is an official client writer used only to count written points
+	counter  Writer
+	lock     sync.Mutex
+	buffer   []byte
+	buffered int
+	stats    WriteStats
+}
+
+func NewWriterUDP(addr string, config WriterConfig, counter Writer) *WriterUDP {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		panic(err)
+	}
+	p := &WriterUDP{
+		conn:         conn,
+		payloadBytes: config.UDPPayloadBytes,
+		batchSize:    int(config.BatchSize),
+		points:       config.Points,
+		counter:      counter,
+		sizes:        newBatchSizes(config),
+	}
+	p.target = p.sizes.next(0, p.batchSize)
+	if p.payloadBytes == 0 {
+		p.payloadBytes = DefaultUDPPayloadBytes
+	}
+	return p
+}
+
+// udpAddr returns WriterConfig.UDPAddr or the host of serverUrl with the default port 8089 of the UDP listener
+func udpAddr(serverUrl string, config WriterConfig) string {
+	if config.UDPAddr != "" {
+		return config.UDPAddr
+	}
+	parsed, err := url.Parse(serverUrl)
+	if err != nil {
+		panic(err)
+	}
+	return net.JoinHostPort(parsed.Hostname(), "8089")
+}
+
+func (p *WriterUDP) Write(id int, measurementName string, iterations []int) {
+	var lines []byte
+	for _, iteration := range iterations {
+		lines = appendLineProtocol(lines, measurementName, p.points.seriesId(id, iteration), p.points.RunTag,
+			p.points.fields(iteration), p.points.FloatFormat, p.points.writtenTimestamp(iteration))
+	}
+
+	p.lock.Lock()
+	p.buffer = append(p.buffer, lines...)
+	p.buffered += len(iterations)
+	if p.buffered < p.target {
+		p.lock.Unlock()
+		return
+	}
+	p.target = p.sizes.next(p.buffered, p.batchSize)
+	batch := p.buffer
+	p.buffer = make([]byte, 0, len(batch))
+	p.buffered = 0
+	p.lock.Unlock()
+
+	p.send(batch)
+}
+
+// send splits the batch into datagrams of at most payloadBytes and sends them
+func (p *WriterUDP) send(batch []byte) {
+	atomic.AddInt64(&p.stats.Batches, 1)
+	// the datagram spans from start to end, end is the end of the last line fitting into it
+	start, end := 0, 0
+	for end < len(batch) {
+		lineEnd := end + bytes.IndexByte(batch[end:], '\n') + 1
+		if lineEnd-end > p.payloadBytes {
+			p.sendDatagram(batch[start:end])
+			atomic.AddInt64(&p.stats.OversizePoints, 1)
+			start, end = lineEnd, lineEnd
+			continue
+		}
+		if lineEnd-start > p.payloadBytes {
+			p.sendDatagram(batch[start:end])
+			start = end
+		}
+		end = lineEnd
+	}
+	p.sendDatagram(batch[start:end])
+}
+
+func (p *WriterUDP) sendDatagram(datagram []byte) {
+	if len(datagram) == 0 {
+		return
+	}
+	atomic.AddInt64(&p.stats.Datagrams, 1)
+	atomic.AddInt64(&p.stats.SentBytes, int64(len(datagram)))
+	if _, err := p.conn.Write(datagram); err != nil {
+		atomic.AddInt64(&p.stats.NetworkErrors, 1)
+		return
+	}
+	atomic.AddInt64(&p.stats.WrittenBytes, int64(len(datagram)))
+}
+
+func (p *WriterUDP) WriteStats() WriteStats {
+	return WriteStats{
+		Batches:        atomic.LoadInt64(&p.stats.Batches),
+		SentBytes:      atomic.LoadInt64(&p.stats.SentBytes),
+		WrittenBytes:   atomic.LoadInt64(&p.stats.WrittenBytes),
+		NetworkErrors:  atomic.LoadInt64(&p.stats.NetworkErrors),
+		Datagrams:      atomic.LoadInt64(&p.stats.Datagrams),
+		OversizePoints: atomic.LoadInt64(&p.stats.OversizePoints),
+	}
+}
+
+func (p *WriterUDP) batchSizes() []int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.sizes.recorded()
+}
+
+// flush sends all buffered points
+func (p *WriterUDP) flush() error {
+	p.lock.Lock()
+	if p.buffered > 0 {
+		p.target = p.sizes.next(p.buffered, p.batchSize)
+	}
+	batch := p.buffer
+	p.buffer = nil
+	p.buffered = 0
+	p.lock.Unlock()
+
+	if len(batch) > 0 {
+		p.send(batch)
+	}
+	return nil
+}
+
+// Flush sends all buffered points
+func (p *WriterUDP) Flush() {
+	_ = p.flush()
+}
+
+func (p *WriterUDP) Count(measurementName string) (int, error) {
+	p.Flush()
+	return p.counter.Count(measurementName)
+}
+
+func (p *WriterUDP) readCount(measurementName string) (int, error) {
+	return p.counter.Count(measurementName)
+}
+
+func (p *WriterUDP) seriesTimes(measurementName string, id int) ([]int64, error) {
+	querier, ok := p.counter.(timeQuerier)
+	if !ok {
+		return nil, fmt.Errorf("the counter doesn't query timestamps")
+	}
+	return querier.seriesTimes(measurementName, id)
+}
+
+func (p *WriterUDP) timeRange(measurementName string) (int64, int64, error) {
+	querier, ok := p.counter.(timeQuerier)
+	if !ok {
+		return 0, 0, fmt.Errorf("the counter doesn't query timestamps")
+	}
+	return querier.timeRange(measurementName)
+}
+
+func (p *WriterUDP) countQuery(measurementName string) string {
+	if querier, ok := p.counter.(countQuerier); ok {
+		return querier.countQuery(measurementName)
+	}
+	return ""
+}
+
+func (p *WriterUDP) countRange(measurementName string, start int64, stop int64) (int, error) {
+	counter, ok := p.counter.(rangeCounter)
+	if !ok {
+		return 0, fmt.Errorf("the counter doesn't count time ranges")
+	}
+	return counter.countRange(measurementName, start, stop)
+}
+
+func (p *WriterUDP) Close() error {
+	_ = p.flush()
+	err := p.conn.Close()
+	if closeErr := p.counter.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
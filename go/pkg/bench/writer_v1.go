@@ -0,0 +1,205 @@
+package bench
+
+import (
+	"fmt"
+	_ "github.com/influxdata/influxdb1-client" // this is important because of the bug in go mod
+	"github.com/influxdata/influxdb1-client/models"
+	client "github.com/influxdata/influxdb1-client/v2"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+type WriterV1 struct {
+	influx client.Client
+	points PointOptions
+	// consistency is the write consistency of batches, empty for the default of the server
+	consistency string
+	// countField is the field whose values are counted
+	countField string
+	batches    *BatchTrace
+	// precision is the precision of batches, the client rejects "u" and serializes "us" in nanoseconds, so
+	// microseconds are written as nanoseconds truncated by the benchmark
+	precision string
+	// connections counts connections dialed by the client when counted is set, it is updated atomically
+	connections int64
+	counted     bool
+}
+
+func NewWriterV1(client client.Client, config WriterConfig) *WriterV1 {
+	writer := &WriterV1{
+		influx:      client,
+		points:      config.Points,
+		countField:  config.countField(),
+		consistency: config.WriteConsistency,
+		batches:     config.BatchTrace,
+	}
+	if precision := config.Points.PrecisionV1(); precision != "u" {
+		writer.precision = precision
+	}
+	writer.counted = countConnections(client, &writer.connections, "httpClient")
+	return writer
+}
+
+func (p *WriterV1) Write(id int, measurementName string, iterations []int) {
+
+	bp, _ := client.NewBatchPoints(client.BatchPointsConfig{
+		Database:         "iot_writes",
+		Precision:        p.precision,
+		WriteConsistency: p.consistency,
+	})
+
+	for _, iteration := range iterations {
+		tags := p.points.tags(id, iteration)
+		fields := p.points.fields(iteration)
+		timestamp := p.points.writtenTimestamp(iteration) * int64(p.points.precision())
+		pt, _ := client.NewPoint(measurementName, tags, fields, time.Unix(0, timestamp))
+		bp.AddPoint(pt)
+	}
+	start := time.Now()
+	err := p.influx.Write(bp)
+	p.batches.record("batch", start, len(iterations), 0, 1, err)
+}
+
+// WriteLines parses the lines into points of one batch
+func (p *WriterV1) WriteLines(lines []string) error {
+	parsed, err := models.ParsePointsString(strings.Join(lines, "\n"))
+	if err != nil {
+		return err
+	}
+	bp, _ := client.NewBatchPoints(client.BatchPointsConfig{
+		Database:         "iot_writes",
+		WriteConsistency: p.consistency,
+	})
+	for _, pt := range parsed {
+		bp.AddPoint(client.NewPointFrom(pt))
+	}
+	return p.influx.Write(bp)
+}
+
+func (p *WriterV1) Count(measurementName string) (int, error) {
+	return countInfluxQL(p.influx, "iot_writes", measurementName, p.countField, p.points.RunTag, "")
+}
+
+func (p *WriterV1) countQuery(measurementName string) string {
+	return countCommand(measurementName, p.countField, p.points.RunTag, "")
+}
+
+func (p *WriterV1) countRange(measurementName string, start int64, stop int64) (int, error) {
+	return countInfluxQL(p.influx, "iot_writes", measurementName, p.countField, p.points.RunTag, timeCondition(start, stop))
+}
+
+func (p *WriterV1) seriesTimes(measurementName string, id int) ([]int64, error) {
+	return timesInfluxQL(p.influx, "iot_writes", fmt.Sprintf(`SELECT "%s" FROM %s WHERE "id" = '%v'%s ORDER BY time ASC`,
+		p.countField, measurementName, id, runCondition(" AND", p.points.RunTag)))
+}
+
+func (p *WriterV1) timeRange(measurementName string) (int64, int64, error) {
+	var bounds [2]int64
+	for i, selector := range []string{"first", "last"} {
+		times, err := timesInfluxQL(p.influx, "iot_writes", fmt.Sprintf(`SELECT %s("%s") FROM %s%s`,
+			selector, p.countField, measurementName, runCondition(" WHERE", p.points.RunTag)))
+		if err != nil {
+			return 0, 0, err
+		}
+		if len(times) == 0 {
+			return 0, 0, fmt.Errorf("no points of %s found", measurementName)
+		}
+		bounds[i] = times[0]
+	}
+	return bounds[0], bounds[1], nil
+}
+
+// runCondition returns the condition of the run tag prefixed by the keyword, it is empty for empty runTag
+func runCondition(keyword string, runTag string) string {
+	if runTag == "" {
+		return ""
+	}
+	return keyword + ` "run" = '` + runTag + `'`
+}
+
+// countCommand returns the InfluxQL query of countInfluxQL
+func countCommand(measurementName string, field string, runTag string, condition string) string {
+	command := fmt.Sprintf(`SELECT count("%s") FROM %s`, field, measurementName)
+	if runTag != "" {
+		command += ` WHERE "run" = '` + runTag + `'`
+	}
+	if condition != "" && runTag != "" {
+		command += " AND " + condition
+	} else if condition != "" {
+		command += " WHERE " + condition
+	}
+	return command
+}
+
+// timeCondition returns the InfluxQL condition of timestamps from start inclusive to stop exclusive in nanoseconds
+func timeCondition(start int64, stop int64) string {
+	return fmt.Sprintf("time >= %d AND time < %d", start, stop)
+}
+
+// timesInfluxQL returns the times of rows of the InfluxQL query in nanoseconds
+func timesInfluxQL(influx client.Client, database string, command string) ([]int64, error) {
+	response, err := influx.Query(client.NewQuery(command, database, "ns"))
+	if err != nil {
+		return nil, err
+	}
+	if response.Error() != nil {
+		return nil, response.Error()
+	}
+	var times []int64
+	for _, result := range response.Results {
+		for _, series := range result.Series {
+			for _, values := range series.Values {
+				nanos, err := strconv.ParseInt(fmt.Sprintf("%v", values[0]), 10, 64)
+				if err != nil {
+					return nil, err
+				}
+				times = append(times, nanos)
+			}
+		}
+	}
+	return times, nil
+}
+
+func (p *WriterV1) Connections() (int64, bool) {
+	return atomic.LoadInt64(&p.connections), p.counted
+}
+
+func (p *WriterV1) Close() error { return p.influx.Close() }
+
+// countInfluxQL counts values of the field in the measurement by InfluxQL query,
+// the count column is located by its name and counts of all returned series are summed,
+// only points of the run are counted for not empty runTag and only points matching the not empty condition
+func countInfluxQL(influx client.Client, database string, measurementName string, field string, runTag string, condition string) (int, error) {
+	q := client.NewQuery(countCommand(measurementName, field, runTag, condition), database, "")
+	response, err := influx.Query(q)
+	if err != nil {
+		return 0, err
+	}
+	if response.Error() != nil {
+		return 0, response.Error()
+	}
+	total := 0
+	for _, result := range response.Results {
+		for _, series := range result.Series {
+			column := -1
+			for i, name := range series.Columns {
+				if name == "count" || name == "count_"+field {
+					column = i
+				}
+			}
+			if column < 0 {
+				return 0, fmt.Errorf("count_%s column not found in %v", field, series.Columns)
+			}
+			for _, values := range series.Values {
+				count, err := strconv.Atoi(fmt.Sprintf("%v", values[column]))
+				if err != nil {
+					return 0, err
+				}
+				total += count
+			}
+		}
+	}
+	return total, nil
+}
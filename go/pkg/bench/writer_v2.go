@@ -0,0 +1,498 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"github.com/influxdata/influxdb-client-go"
+	"github.com/influxdata/influxdb-client-go/api"
+	"github.com/influxdata/influxdb-client-go/api/write"
+	client "github.com/influxdata/influxdb1-client/v2"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type WriterV2 struct {
+	influx   influxdb2.Client
+	writeApi api.WriteApi
+	// writeApiBlocking is set instead of writeApi for writers that write every Write call synchronously
+	writeApiBlocking api.WriteApiBlocking
+	// failedWrites counts failed synchronous writes, it is updated atomically
+	failedWrites int64
+	// connection measures the setup of the first connection of synchronous writes
+	connection connectionTrace
+	points     PointOptions
+	// countLang is the language of the count query, "flux" or "influxql" through the 1.x compatibility endpoint
+	countLang string
+	// lightweight counts by the flux query without pivot, see WriterConfig.LightweightCount
+	lightweight bool
+	// countStart and countStop are the range of flux queries, see WriterConfig.CountStart
+	countStart string
+	countStop  string
+	// countField is the field whose values are counted
+	countField string
+	authToken  string
+	org        string
+	bucket     string
+	// onError is the policy of failed synchronous writes, it is nil for asynchronous writers
+	onError *errorPolicy
+	// pending are synchronous writes that failed and were requeued by the OnError policy
+	pendingLock sync.Mutex
+	pending     []pendingWrite
+	// bound limits pending points of the asynchronous write API, it is nil without the bound
+	bound *pendingGuard
+	// records passes points serialized into line protocol to WriteRecord instead of WritePoint
+	records bool
+	// reused are the lines of WriterConfig.ReusePoints written as records, it is nil otherwise
+	reused *reusedLines
+	// deadline bounds synchronous writes
+	deadline writeDeadline
+	// batches traces every synchronous write, or every flush of the asynchronous write API
+	batches *BatchTrace
+	// deadLetters receives the points of synchronous writes that are not requeued after they failed
+	deadLetters *DeadLetters
+	// locks measures the wait for pendingLock and the lock of bound, it is nil without WriterConfig.ReportLockWait
+	locks *lockTimer
+	// connections counts connections dialed by the client when counted is set, it is updated atomically
+	connections int64
+	counted     bool
+}
+
+func NewWriterV2(client influxdb2.Client, config WriterConfig) *WriterV2 {
+	locks := newLockTimer(config)
+	writer := &WriterV2{
+		influx:      client,
+		writeApi:    client.WriteApi(config.org(), config.bucket()),
+		points:      config.Points,
+		countLang:   config.CountLang,
+		lightweight: config.LightweightCount,
+		countStart:  config.countStart(),
+		countStop:   config.countStop(),
+		countField:  config.countField(),
+		authToken:   config.AuthToken,
+		org:         config.org(),
+		bucket:      config.bucket(),
+		records:     config.V2WriteMode == "record",
+		reused:      newReusedLines(config),
+		bound:       newPendingGuard(config, locks),
+		batches:     config.BatchTrace,
+		locks:       locks,
+	}
+	writer.counted = countConnections(client, &writer.connections, "httpService", "client")
+	if writer.bound != nil {
+		// the write API doesn't tell about batches it sends by itself, they are observed by its requests
+		wrapTransport(client, func(transport http.RoundTripper) http.RoundTripper {
+			return &sentTransport{transport: transport, sent: writer.bound.sent}
+		}, "httpService", "client")
+	}
+	return writer
+}
+
+func newPendingGuard(config WriterConfig, locks *lockTimer) *pendingGuard {
+	if config.MaxPendingPoints <= 0 {
+		return nil
+	}
+	return &pendingGuard{max: config.MaxPendingPoints, drop: config.PendingPolicy == "drop", locks: locks}
+}
+
+// pendingWrite is a requeued Write call, its points or records are written again unchanged
+type pendingWrite struct {
+	points  []*write.Point
+	records []string
+	// err is the error of the last attempt
+	err error
+}
+
+// size returns the count of points of the write
+func (w pendingWrite) size() int {
+	if w.records != nil {
+		return len(w.records)
+	}
+	return len(w.points)
+}
+
+// NewWriterV2Blocking creates a V2 writer that writes points of every Write call by one blocking request
+func NewWriterV2Blocking(client influxdb2.Client, config WriterConfig) *WriterV2 {
+	writer := &WriterV2{
+		influx:           client,
+		writeApiBlocking: client.WriteApiBlocking(config.org(), config.bucket()),
+		points:           config.Points,
+		countLang:        config.CountLang,
+		lightweight:      config.LightweightCount,
+		countStart:       config.countStart(),
+		countStop:        config.countStop(),
+		countField:       config.countField(),
+		authToken:        config.AuthToken,
+		org:              config.org(),
+		bucket:           config.bucket(),
+		records:          config.V2WriteMode == "record",
+		reused:           newReusedLines(config),
+		onError:          newErrorPolicy(config.OnError),
+		deadline:         writeDeadline{timeout: config.WriteTimeout},
+		locks:            newLockTimer(config),
+		batches:          config.BatchTrace,
+		deadLetters:      config.DeadLetters,
+	}
+	writer.counted = countConnections(client, &writer.connections, "httpService", "client")
+	return writer
+}
+
+func (p *WriterV2) Write(id int, measurementName string, iterations []int) {
+	if p.writeApiBlocking != nil {
+		p.writePending()
+	}
+	if p.records {
+		p.writeRecords(id, measurementName, iterations)
+		return
+	}
+	p.writePoints(id, measurementName, iterations)
+}
+
+// writePoints writes the points by WritePoint of the client
+func (p *WriterV2) writePoints(id int, measurementName string, iterations []int) {
+	var points []*write.Point
+	for _, iteration := range iterations {
+		point := influxdb2.NewPoint(
+			measurementName,
+			p.points.tags(id, iteration),
+			p.points.fields(iteration),
+			time.Unix(0, p.points.timestamp(iteration)))
+
+		if p.writeApiBlocking != nil {
+			points = append(points, point)
+			continue
+		}
+		if p.admit() {
+			p.writeApi.WritePoint(point)
+		}
+	}
+	if p.writeApiBlocking != nil {
+		p.sendPoints(pendingWrite{points: points})
+	}
+}
+
+// sendPoints writes the points of the write by one blocking request
+func (p *WriterV2) sendPoints(write pendingWrite) {
+	ctx, cancel := p.deadline.context(context.Background())
+	defer cancel()
+	ctx, traced := p.connection.trace(ctx)
+	start := time.Now()
+	err := p.writeApiBlocking.WritePoint(ctx, write.points...)
+	p.batches.record("batch", start, len(write.points), 0, 1, err)
+	traced(err)
+	if err != nil {
+		write.err = err
+		p.failed(write)
+	}
+}
+
+// failed counts the failed synchronous write and applies the OnError policy to it
+func (p *WriterV2) failed(write pendingWrite) {
+	atomic.AddInt64(&p.failedWrites, 1)
+	if p.onError.failed(write.size(), write.err) {
+		p.locks.lock(&p.pendingLock)
+		p.pending = append(p.pending, write)
+		p.pendingLock.Unlock()
+	} else {
+		p.deadLetters.add(p.lines(write), write.size(), write.err)
+	}
+}
+
+// lines returns the line protocol of the write as it was sent, the points are serialized like the client does
+func (p *WriterV2) lines(pending pendingWrite) []byte {
+	var lines []byte
+	for _, record := range pending.records {
+		lines = append(append(lines, record...), '\n')
+	}
+	for _, point := range pending.points {
+		lines = append(lines, write.PointToLineProtocol(point, p.influx.Options().Precision())...)
+	}
+	return lines
+}
+
+// writePending writes the requeued writes again
+func (p *WriterV2) writePending() {
+	p.locks.lock(&p.pendingLock)
+	pending := p.pending
+	p.pending = nil
+	p.pendingLock.Unlock()
+	for _, write := range pending {
+		if p.records {
+			p.sendRecords(write)
+		} else {
+			p.sendPoints(write)
+		}
+	}
+}
+
+// ErrorStats returns outcomes of failed synchronous writes, they are empty for asynchronous writers
+func (p *WriterV2) ErrorStats() ErrorStats {
+	if p.onError == nil {
+		return ErrorStats{}
+	}
+	return p.onError.stats()
+}
+
+func (p *WriterV2) lockWait() time.Duration {
+	return p.locks.total()
+}
+
+func (p *WriterV2) runEnds(end time.Time) {
+	p.deadline.runEnds(end)
+}
+
+func (p *WriterV2) aborted() <-chan bool {
+	if p.onError == nil {
+		return nil
+	}
+	return p.onError.aborted()
+}
+
+// writeRecords writes the points serialized into line protocol by the benchmark, skipping serialization of the client
+func (p *WriterV2) writeRecords(id int, measurementName string, iterations []int) {
+	var lines []string
+	for _, iteration := range iterations {
+		line := p.reused.appendLine(nil, p.points, id, measurementName, iteration)
+		// the client terminates every record by a newline itself
+		record := string(line[:len(line)-1])
+		if p.writeApiBlocking != nil {
+			lines = append(lines, record)
+			continue
+		}
+		if p.admit() {
+			p.writeApi.WriteRecord(record)
+		}
+	}
+	if p.writeApiBlocking != nil {
+		p.sendRecords(pendingWrite{records: lines})
+	}
+}
+
+// sendRecords writes the records of the write by one blocking request
+func (p *WriterV2) sendRecords(write pendingWrite) {
+	ctx, cancel := p.deadline.context(context.Background())
+	defer cancel()
+	ctx, traced := p.connection.trace(ctx)
+	start := time.Now()
+	err := p.writeApiBlocking.WriteRecord(ctx, write.records...)
+	p.batches.record("batch", start, len(write.records), 0, 1, err)
+	traced(err)
+	if err != nil {
+		write.err = err
+		p.failed(write)
+	}
+}
+
+// WriteLines writes the lines by WriteRecord, the asynchronous write API only buffers them
+func (p *WriterV2) WriteLines(lines []string) error {
+	if p.writeApiBlocking != nil {
+		ctx, cancel := p.deadline.context(context.Background())
+		defer cancel()
+		return p.writeApiBlocking.WriteRecord(ctx, lines...)
+	}
+	for _, line := range lines {
+		p.writeApi.WriteRecord(line)
+	}
+	return nil
+}
+
+// ConnectionSetup returns the setup of the first connection, it is measured only for synchronous writes
+func (p *WriterV2) ConnectionSetup() ConnectionSetup {
+	return p.connection.connectionSetup()
+}
+
+func (p *WriterV2) Connections() (int64, bool) {
+	return atomic.LoadInt64(&p.connections), p.counted
+}
+
+// admit tells whether the point can be handed to the asynchronous write API within the bound of pending points
+func (p *WriterV2) admit() bool {
+	return p.bound == nil || p.bound.admit(p.flushApi)
+}
+
+// flushApi flushes the asynchronous write API, the batches it fires are not observable so the flush is traced instead
+func (p *WriterV2) flushApi() {
+	start := time.Now()
+	p.writeApi.Flush()
+	p.batches.record("flush", start, 0, 0, 0, nil)
+}
+
+// PendingStats returns outcomes of the bound of pending points, they are empty without the bound
+func (p *WriterV2) PendingStats() PendingStats {
+	if p.bound == nil {
+		return PendingStats{}
+	}
+	return p.bound.pendingStats()
+}
+
+// Flush writes points buffered by the asynchronous write API, or the requeued synchronous writes
+func (p *WriterV2) Flush() {
+	if p.writeApi != nil {
+		if p.bound != nil {
+			p.bound.wait()
+		}
+		p.flushApi()
+		if p.bound != nil {
+			p.bound.flushed()
+		}
+	} else {
+		p.writePending()
+	}
+}
+
+func (p *WriterV2) Count(measurementName string) (int, error) {
+	return p.count(measurementName, "", p.fluxSource(measurementName))
+}
+
+func (p *WriterV2) countRange(measurementName string, start int64, stop int64) (int, error) {
+	return p.count(measurementName, timeCondition(start, stop),
+		p.fluxRangeSource(measurementName, fmt.Sprintf("time(v: %d)", start), fmt.Sprintf("time(v: %d)", stop)))
+}
+
+// count counts the points by the InfluxQL condition, or by the flux source of values of the counted field,
+// counts of all returned records are summed and no records mean no points
+func (p *WriterV2) count(measurementName string, condition string, source string) (int, error) {
+	if p.countLang == "influxql" {
+		// the 1.x compatibility endpoint accepts the token as the password of basic authentication
+		influx, err := client.NewHTTPClient(client.HTTPConfig{
+			Addr:     p.influx.ServerUrl(),
+			Username: "benchmark",
+			Password: p.authToken,
+		})
+		if err != nil {
+			return 0, err
+		}
+		defer influx.Close()
+		return countInfluxQL(influx, p.bucket, measurementName, p.countField, p.points.RunTag, condition)
+	}
+
+	queryResult, err := p.influx.QueryApi(p.org).Query(context.Background(), p.countFlux(source))
+	if err != nil {
+		return 0, err
+	}
+	column := p.countField
+	if p.lightweight {
+		column = "_value"
+	}
+	total := 0
+	for queryResult.Next() {
+		count, ok := queryResult.Record().ValueByKey(column).(int64)
+		if !ok {
+			return 0, fmt.Errorf("count of the %s field not found in %v", p.countField, queryResult.Record().Values())
+		}
+		total += int(count)
+	}
+	if queryResult.Err() != nil {
+		return 0, queryResult.Err()
+	}
+	return total, nil
+}
+
+// countTagged counts the points of the measurement having all the tags
+func (p *WriterV2) countTagged(measurementName string, tags map[string]string) (int, error) {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var conditions, filters []string
+	for _, key := range keys {
+		conditions = append(conditions, fmt.Sprintf(`"%s" = '%s'`, key, tags[key]))
+		filters = append(filters, fmt.Sprintf(`r["%s"] == "%s"`, key, tags[key]))
+	}
+	return p.count(measurementName, strings.Join(conditions, " AND "), p.fluxSource(measurementName)+`
+		|> filter(fn: (r) => `+strings.Join(filters, " and ")+`)`)
+}
+
+func (p *WriterV2) countQuery(measurementName string) string {
+	if p.countLang == "influxql" {
+		return countCommand(measurementName, p.countField, p.points.RunTag, "")
+	}
+	return p.countFlux(p.fluxSource(measurementName))
+}
+
+// countFlux returns the flux query counting points of the source
+func (p *WriterV2) countFlux(source string) string {
+	if p.lightweight {
+		return source + `
+		|> group()
+		|> count(column: "_value")`
+	}
+	return source + `
+		|> pivot(rowKey:["_time"], columnKey: ["_field"], valueColumn: "_value")
+		|> group()
+		|> count(column: "` + p.countField + `")`
+}
+
+// fluxSource returns the flux query of values of the counted field of the measurement
+func (p *WriterV2) fluxSource(measurementName string) string {
+	return p.fluxRangeSource(measurementName, p.countStart, p.countStop)
+}
+
+// fluxRangeSource returns the flux query of values of the counted field of the measurement in the range
+// of the flux expressions start and stop
+func (p *WriterV2) fluxRangeSource(measurementName string, start string, stop string) string {
+	runFilter := ""
+	if p.points.RunTag != "" {
+		runFilter = `
+		|> filter(fn: (r) => r.run == "` + p.points.RunTag + `")`
+	}
+	return `from(bucket:"` + p.bucket + `") 
+		|> range(start: ` + start + `, stop: ` + stop + `) 
+		|> filter(fn: (r) => r._measurement == "` + measurementName + `") 
+		|> filter(fn: (r) => r._field == "` + p.countField + `")` + runFilter
+}
+
+// queryTimes returns the times of records of the flux query
+func (p *WriterV2) queryTimes(query string) ([]int64, error) {
+	queryResult, err := p.influx.QueryApi(p.org).Query(context.Background(), query)
+	if err != nil {
+		return nil, err
+	}
+	var times []int64
+	for queryResult.Next() {
+		times = append(times, queryResult.Record().Time().UnixNano())
+	}
+	return times, queryResult.Err()
+}
+
+func (p *WriterV2) seriesTimes(measurementName string, id int) ([]int64, error) {
+	return p.queryTimes(p.fluxSource(measurementName) + `
+		|> filter(fn: (r) => r.id == "` + strconv.Itoa(id) + `")
+		|> keep(columns: ["_time"])
+		|> sort(columns: ["_time"])`)
+}
+
+func (p *WriterV2) timeRange(measurementName string) (int64, int64, error) {
+	var bounds [2]int64
+	for i, selector := range []string{"min", "max"} {
+		times, err := p.queryTimes(p.fluxSource(measurementName) + `
+		|> keep(columns: ["_time"])
+		|> group()
+		|> ` + selector + `(column: "_time")`)
+		if err != nil {
+			return 0, 0, err
+		}
+		if len(times) == 0 {
+			return 0, 0, fmt.Errorf("no points of %s found", measurementName)
+		}
+		bounds[i] = times[0]
+	}
+	return bounds[0], bounds[1], nil
+}
+
+func (p *WriterV2) Close() error {
+	// the requeued writes are never written again
+	p.pendingLock.Lock()
+	for _, write := range p.pending {
+		p.deadLetters.add(p.lines(write), write.size(), write.err)
+	}
+	p.pending = nil
+	p.pendingLock.Unlock()
+	p.influx.Close()
+	return nil
+}
@@ -0,0 +1,197 @@
+package bench
+
+import (
+	"sync"
+	"time"
+)
+
+// rotationErrorWindow is how close to a rotation a write error has to occur to be counted as around the rotation
+const rotationErrorWindow = time.Second
+
+// WriterV2Rotating is a V2 writer that rebuilds its client and write API with the next token of its list
+// at every interval. The replaced write API is closed after the swap, so its in-flight batches are written
+// with the previous token.
+type WriterV2Rotating struct {
+	serverUrl string
+	config    WriterConfig
+	tokens    []string
+	interval  time.Duration
+	// lock guards current against the swap at rotation
+	lock    sync.RWMutex
+	current *WriterV2
+	next    int
+	stop    chan bool
+	done    chan bool
+	// drains reads errors of asynchronous writes of all built write APIs
+	drains sync.WaitGroup
+	// closing is held by the rotation until the replaced writer has written its buffer
+	closing   sync.Mutex
+	statsLock sync.Mutex
+	rotations []time.Time
+	errors    []time.Time
+	lastError error
+	// replaced are the outcomes of the pending bounds of the replaced writers
+	replaced PendingStats
+}
+
+// RotationStats counts token rotations and errors of asynchronous writes
+type RotationStats struct {
+	Rotations int
+	Errors    int
+	// ErrorsAroundRotation counts errors that occurred within a second of a rotation
+	ErrorsAroundRotation int
+	// LastError is the last error of an asynchronous write, it is nil without errors
+	LastError error
+}
+
+// RotationReporter is implemented by writers that rotate their authentication tokens
+type RotationReporter interface {
+	RotationStats() RotationStats
+}
+
+// NewWriterV2Rotating creates a V2 writer that starts with the first token and switches to the next one,
+// cycling through tokens, at every interval
+func NewWriterV2Rotating(serverUrl string, tokens []string, interval time.Duration, config WriterConfig) *WriterV2Rotating {
+	p := &WriterV2Rotating{
+		serverUrl: serverUrl,
+		config:    config,
+		tokens:    tokens,
+		interval:  interval,
+		stop:      make(chan bool),
+		done:      make(chan bool),
+	}
+	p.current = p.build()
+	go p.rotate()
+	return p
+}
+
+// build creates the V2 writer with the next token and starts draining errors of its write API
+func (p *WriterV2Rotating) build() *WriterV2 {
+	config := p.config
+	config.AuthToken = p.tokens[p.next%len(p.tokens)]
+	p.next++
+	writer := NewWriterV2(newClientV2(p.serverUrl, config.AuthToken, config.BatchSize, config.Points.precision(), config.DefaultTags), config)
+	errs := writer.writeApi.Errors()
+	p.drains.Add(1)
+	go func() {
+		defer p.drains.Done()
+		for err := range errs {
+			p.statsLock.Lock()
+			p.errors = append(p.errors, time.Now())
+			p.lastError = err
+			p.statsLock.Unlock()
+		}
+	}()
+	return writer
+}
+
+// rotate replaces the current writer at every interval until the writer is closed
+func (p *WriterV2Rotating) rotate() {
+	defer close(p.done)
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+		p.closing.Lock()
+		writer := p.build()
+		p.lock.Lock()
+		previous := p.current
+		p.current = writer
+		p.lock.Unlock()
+		p.statsLock.Lock()
+		p.rotations = append(p.rotations, time.Now())
+		p.statsLock.Unlock()
+		previous.Close()
+		p.statsLock.Lock()
+		p.replaced = addPendingStats(p.replaced, previous.PendingStats())
+		p.statsLock.Unlock()
+		p.closing.Unlock()
+	}
+}
+
+func (p *WriterV2Rotating) Write(id int, measurementName string, iterations []int) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	p.current.Write(id, measurementName, iterations)
+}
+
+// Flush writes points buffered by the current write API and waits for the replaced one
+func (p *WriterV2Rotating) Flush() {
+	p.lock.RLock()
+	p.current.Flush()
+	p.lock.RUnlock()
+	p.closing.Lock()
+	p.closing.Unlock()
+}
+
+func (p *WriterV2Rotating) Count(measurementName string) (int, error) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.current.Count(measurementName)
+}
+
+func (p *WriterV2Rotating) countQuery(measurementName string) string {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.current.countQuery(measurementName)
+}
+
+func (p *WriterV2Rotating) countTagged(measurementName string, tags map[string]string) (int, error) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.current.countTagged(measurementName, tags)
+}
+
+// ErrorStats returns the outcomes of the current writer, the asynchronous writers don't apply the OnError policy
+func (p *WriterV2Rotating) ErrorStats() ErrorStats {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.current.ErrorStats()
+}
+
+func (p *WriterV2Rotating) aborted() <-chan bool {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.current.aborted()
+}
+
+// PendingStats returns the outcomes of the pending bounds of the current and the replaced writers
+func (p *WriterV2Rotating) PendingStats() PendingStats {
+	p.lock.RLock()
+	current := p.current.PendingStats()
+	p.lock.RUnlock()
+	p.statsLock.Lock()
+	defer p.statsLock.Unlock()
+	return addPendingStats(p.replaced, current)
+}
+
+func (p *WriterV2Rotating) RotationStats() RotationStats {
+	p.statsLock.Lock()
+	defer p.statsLock.Unlock()
+	stats := RotationStats{
+		Rotations: len(p.rotations),
+		Errors:    len(p.errors),
+		LastError: p.lastError,
+	}
+	for _, occurred := range p.errors {
+		for _, rotated := range p.rotations {
+			if occurred.Sub(rotated) < rotationErrorWindow && rotated.Sub(occurred) < rotationErrorWindow {
+				stats.ErrorsAroundRotation++
+				break
+			}
+		}
+	}
+	return stats
+}
+
+func (p *WriterV2Rotating) Close() error {
+	close(p.stop)
+	<-p.done
+	err := p.current.Close()
+	p.drains.Wait()
+	return err
+}
@@ -39,7 +39,7 @@ SCRIPT_PATH="$(
 cd "${SCRIPT_PATH}"/../
 mvn clean compile assembly:single
 cd "${SCRIPT_PATH}"/../go
-go build -o ./bin/benchmark ./cmd/main.go
+go build -ldflags "-X main.commit=$(git rev-parse --short HEAD)" -o ./bin/benchmark ./cmd
 
 declare -a types=("CLIENT_V1_OPTIMIZED" "CLIENT_V1" "HTTP_V1" "CLIENT_V2_OPTIMIZED" "CLIENT_V2" "HTTP_V2" "CLIENT_GO_V2")
 for i in "${types[@]}"; do
@@ -18,7 +18,7 @@ cd "${SCRIPT_PATH}"/../
 #mvn -quiet clean compile assembly:single
 #echo "Compile go benchmarks"
 #cd "${SCRIPT_PATH}"/../go
-#go build -o ./bin/benchmark ./cmd/main.go
+#go build -o ./bin/benchmark ./cmd
 
 function run_benchmark() {
 
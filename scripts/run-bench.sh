@@ -18,7 +18,7 @@ echo "Compile java benchmarks..."
 mvn -quiet clean compile assembly:single
 echo "Compile go benchmarks"
 cd "${SCRIPT_PATH}"/../go
-go build -o ./bin/benchmark ./cmd/main.go
+go build -ldflags "-X main.commit=$(git rev-parse --short HEAD)" -o ./bin/benchmark ./cmd
 cd "${SCRIPT_PATH}"/../csharp
 echo "Compile c# benchmarks"
 dotnet restore